@@ -0,0 +1,782 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// knownImports maps the package names the generated code may use to their import path
+var knownImports = map[string]string{
+	"bytes":   "bytes",
+	"context": "context",
+	"json":    "encoding/json",
+	"fmt":     "fmt",
+	"http":    "net/http",
+	"url":     "net/url",
+	"strings": "strings",
+	"time":    "time",
+	"query":   "github.com/atomgunlk/golang-common/pkg/querystring/query",
+	"request": "github.com/atomgunlk/golang-common/pkg/request",
+}
+
+type generator struct {
+	doc *document
+	// idents are the package level identifiers
+	idents  names
+	methods names
+	// schemas maps a component schema to its Go type
+	schemas map[string]string
+	// plain are the named types which are already nilable, slices or maps
+	plain   map[string]bool
+	hoisted map[*schema]string
+	decls   bytes.Buffer
+	err     error
+}
+
+// generate renders the typed client of doc as a formatted Go file
+func generate(doc *document, pkg, source string) ([]byte, error) {
+	g := &generator{
+		doc:     doc,
+		idents:  names{"APIError": true, "Client": true, "DefaultBaseURL": true, "NewClient": true, "checkStatus": true, "decodeJSON": true, "headerValue": true, "pathParam": true},
+		methods: names{},
+		schemas: make(map[string]string),
+		plain:   make(map[string]bool),
+		hoisted: make(map[*schema]string),
+	}
+
+	for _, name := range doc.Components.Schemas.keys {
+		typ := g.idents.unique(exportName(name))
+		g.schemas[name] = typ
+		if isPlain(doc.Components.Schemas.values[name]) {
+			g.plain[typ] = true
+		}
+	}
+	for _, name := range doc.Components.Schemas.keys {
+		g.declareSchema(g.schemas[name], doc.Components.Schemas.values[name])
+	}
+
+	var ops bytes.Buffer
+	for _, path := range doc.Paths.keys {
+		item := doc.Paths.values[path]
+		methods, operations := item.operations()
+		for i, op := range operations {
+			g.operation(&ops, path, methods[i], item, op)
+		}
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var body bytes.Buffer
+	g.writeClient(&body)
+	body.Write(ops.Bytes())
+	body.Write(g.decls.Bytes())
+	imports, err := usedImports(body.Bytes())
+	if err != nil {
+		return body.Bytes(), fmt.Errorf("parse generated code: %w", err)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by openapi-gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&out, "// Package %s is the client of %s %s\n", pkg, doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	writeImports(&out, imports)
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+func (g *generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// usedImports returns the import paths of the packages referenced by the generated declarations
+func usedImports(src []byte) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package generated\n\n"), src...), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+				if path, ok := knownImports[ident.Name]; ok {
+					used[path] = true
+				}
+			}
+		}
+		return true
+	})
+
+	imports := make([]string, 0, len(used))
+	for path := range used {
+		imports = append(imports, path)
+	}
+	return imports, nil
+}
+
+func writeImports(out *bytes.Buffer, imports []string) {
+	if len(imports) == 0 {
+		return
+	}
+
+	var std, others []string
+	for _, path := range imports {
+		if strings.Contains(path, ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+
+	out.WriteString("import (\n")
+	for _, path := range std {
+		fmt.Fprintf(out, "\t%q\n", path)
+	}
+	if len(std) > 0 && len(others) > 0 {
+		out.WriteString("\n")
+	}
+	for _, path := range others {
+		fmt.Fprintf(out, "\t%q\n", path)
+	}
+	out.WriteString(")\n\n")
+}
+
+func (g *generator) writeClient(out *bytes.Buffer) {
+	baseURL := ""
+	if len(g.doc.Servers) > 0 {
+		baseURL = g.doc.Servers[0].URL
+	}
+
+	fmt.Fprintf(out, `// DefaultBaseURL is the first server of the document
+const DefaultBaseURL = %q
+
+// Client calls the operations of the API through a request.Client
+type Client struct {
+	BaseURL string
+	HTTP    request.Client
+	// Options are sent with every call, such as authentication headers, default query params
+	// or call settings. The query params of an operation take precedence over the default ones
+	Options request.SendOptions
+}
+
+// NewClient returns a client of the API served at baseURL, DefaultBaseURL when empty
+func NewClient(baseURL string, c request.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{BaseURL: baseURL, HTTP: c}
+}
+
+// APIError is returned with a response out of the 2xx range.
+// Payload holds the decoded body when the operation declares a schema for the status
+type APIError struct {
+	StatusCode int
+	Payload    interface{}
+	Response   *request.Response
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api: status %%d: %%s", e.StatusCode, strings.TrimSpace(string(e.Response.Body)))
+}
+
+func (c *Client) send(ctx context.Context, method, path string, params interface{}, headers map[string]string, contentType, accept string, payload []byte) (*request.Response, error) {
+	target := strings.TrimRight(c.BaseURL, "/") + path
+	values, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range c.Options[request.QueryParam] {
+		if _, ok := values[key]; !ok {
+			values.Set(key, fmt.Sprint(val))
+		}
+	}
+	if encoded := values.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+
+	opts := request.SendOptions{request.HeaderParam: {}}
+	if accept != "" {
+		opts[request.HeaderParam]["Accept"] = accept
+	}
+	for key, val := range c.Options {
+		if key != request.HeaderParam && key != request.QueryParam {
+			opts[key] = val
+		}
+	}
+	for key, val := range c.Options[request.HeaderParam] {
+		opts[request.HeaderParam][key] = val
+	}
+	for key, val := range headers {
+		opts[request.HeaderParam][key] = val
+	}
+	if contentType != "" {
+		opts[request.HeaderParam]["Content-Type"] = contentType
+	}
+
+	return request.SendWithContext(ctx, c.HTTP, method, target, opts, payload)
+}
+
+// checkStatus returns an *APIError for a response out of the 2xx range,
+// payload gives the value decoding the body of a status, nil when undeclared
+func checkStatus(resp *request.Response, payload func(status int) interface{}) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Response: resp}
+	if payload != nil {
+		if v := payload(resp.StatusCode); v != nil && decodeJSON(resp, v) == nil {
+			apiErr.Payload = v
+		}
+	}
+	return apiErr
+}
+
+func decodeJSON(resp *request.Response, v interface{}) error {
+	if len(bytes.TrimSpace(resp.Body)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Body, v)
+}
+
+func pathParam(v interface{}) string {
+	return url.PathEscape(fmt.Sprint(v))
+}
+
+func headerValue(v interface{}) string {
+	if values, ok := v.([]string); ok {
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+`, baseURL)
+}
+
+// isPlain reports whether the Go type of a component schema is a slice, a map or an interface
+func isPlain(s *schema) bool {
+	if s.Ref != "" || len(s.AllOf) > 0 || len(s.Properties.keys) > 0 {
+		return false
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return true
+	}
+	switch s.Type.name {
+	case "array", "object", "":
+		return true
+	case "string":
+		return s.Format == "byte"
+	}
+	return false
+}
+
+// nilable reports whether nil is a value of typ
+func (g *generator) nilable(typ string) bool {
+	return strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") ||
+		typ == "interface{}" || typ == "json.RawMessage" || g.plain[typ]
+}
+
+// goType returns the Go type of s, hint names the struct declared for an inline object
+func (g *generator) goType(s *schema, hint string) string {
+	switch {
+	case s == nil:
+		return "interface{}"
+	case s.Ref != "":
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			g.fail(err)
+			return "interface{}"
+		}
+		typ, ok := g.schemas[name]
+		if !ok {
+			g.fail(fmt.Errorf("unknown schema %q", name))
+			return "interface{}"
+		}
+		return typ
+	case len(s.AllOf) > 0 || len(s.Properties.keys) > 0:
+		if name, ok := g.hoisted[s]; ok {
+			return name
+		}
+		name := g.idents.unique(exportName(hint))
+		g.hoisted[s] = name
+		g.declareStruct(name, s)
+		return name
+	case len(s.OneOf) > 0 || len(s.AnyOf) > 0:
+		return "json.RawMessage"
+	}
+
+	switch s.Type.name {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, hint+"Item")
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, hint+"Value")
+		}
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+func (g *generator) declareSchema(name string, s *schema) {
+	var b bytes.Buffer
+	writeDoc(&b, "", name, s.Description)
+
+	switch {
+	case s.Ref != "":
+		fmt.Fprintf(&b, "type %s = %s\n\n", name, g.goType(s, name))
+	case len(s.AllOf) > 0 || len(s.Properties.keys) > 0:
+		g.hoisted[s] = name
+		g.declareStruct(name, s)
+		return
+	case s.Type.name == "string" && len(s.Enum) > 0:
+		fmt.Fprintf(&b, "type %s string\n\n", name)
+		fmt.Fprintf(&b, "// %s values\nconst (\n", name)
+		for _, value := range s.Enum {
+			text := fmt.Sprint(value)
+			suffix := exportName(text)
+			if strings.TrimSpace(text) == "" {
+				suffix = "Empty"
+			}
+			fmt.Fprintf(&b, "\t%s %s = %q\n", g.idents.unique(name+suffix), name, text)
+		}
+		b.WriteString(")\n\n")
+	default:
+		fmt.Fprintf(&b, "type %s %s\n\n", name, g.goType(s, name))
+	}
+	g.decls.Write(b.Bytes())
+}
+
+// declareStruct declares a struct holding the properties of s and of its allOf schemas,
+// the referenced allOf schemas are embedded
+func (g *generator) declareStruct(name string, s *schema) {
+	var b bytes.Buffer
+	writeDoc(&b, "", name, s.Description)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+
+	required := map[string]bool{}
+	var collect func(*schema)
+	collect = func(part *schema) {
+		for _, prop := range part.Required {
+			required[prop] = true
+		}
+		for _, sub := range part.AllOf {
+			collect(sub)
+		}
+	}
+	collect(s)
+
+	fields := names{}
+	var addFields func(*schema)
+	addFields = func(part *schema) {
+		for _, sub := range part.AllOf {
+			if sub.Ref != "" {
+				typ := g.goType(sub, "")
+				fields[typ] = true
+				fmt.Fprintf(&b, "\t%s\n", typ)
+				continue
+			}
+			addFields(sub)
+		}
+		for _, prop := range part.Properties.keys {
+			ps := part.Properties.values[prop]
+			field := fields.unique(exportName(prop))
+			typ := g.goType(ps, name+exportName(prop))
+			tag := prop
+			if !required[prop] {
+				tag += ",omitempty"
+			}
+			if (!required[prop] || ps.Nullable || ps.Type.nullable) && !g.nilable(typ) {
+				typ = "*" + typ
+			}
+			writeDoc(&b, "\t", field, ps.Description)
+			fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, typ, tag)
+		}
+	}
+	addFields(s)
+
+	b.WriteString("}\n\n")
+	g.decls.Write(b.Bytes())
+}
+
+// resolveParameters merges the parameters of the path and of the operation, the latter winning
+func (g *generator) resolveParameters(lists ...[]*parameter) []*parameter {
+	var params []*parameter
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, p := range list {
+			if p.Ref != "" {
+				name, err := refName(p.Ref, "parameters")
+				if err != nil {
+					g.fail(err)
+					continue
+				}
+				if p = g.doc.Components.Parameters[name]; p == nil {
+					g.fail(fmt.Errorf("unknown parameter %q", name))
+					continue
+				}
+			}
+			key := p.In + ":" + p.Name
+			if i, ok := index[key]; ok {
+				params[i] = p
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+func (g *generator) resolveRequestBody(rb *requestBody) *requestBody {
+	if rb == nil || rb.Ref == "" {
+		return rb
+	}
+	name, err := refName(rb.Ref, "requestBodies")
+	if err != nil {
+		g.fail(err)
+		return nil
+	}
+	if resolved := g.doc.Components.RequestBodies[name]; resolved != nil {
+		return resolved
+	}
+	g.fail(fmt.Errorf("unknown request body %q", name))
+	return nil
+}
+
+func (g *generator) resolveResponse(resp *response) *response {
+	if resp == nil || resp.Ref == "" {
+		return resp
+	}
+	name, err := refName(resp.Ref, "responses")
+	if err != nil {
+		g.fail(err)
+		return nil
+	}
+	if resolved := g.doc.Components.Responses[name]; resolved != nil {
+		return resolved
+	}
+	g.fail(fmt.Errorf("unknown response %q", name))
+	return nil
+}
+
+// pickMedia returns the media type of a content, a JSON one when available
+func pickMedia(content map[string]*mediaType) (string, *schema, bool) {
+	if mt, ok := content["application/json"]; ok {
+		return "application/json", mt.Schema, true
+	}
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.Contains(key, "json") {
+			return key, content[key].Schema, true
+		}
+	}
+	if len(keys) > 0 {
+		return keys[0], content[keys[0]].Schema, false
+	}
+	return "", nil, false
+}
+
+// operation writes the method calling op
+func (g *generator) operation(b *bytes.Buffer, path, method string, item *pathItem, op *operation) {
+	opName := op.OperationID
+	if opName == "" {
+		opName = method + " " + path
+	}
+	name := g.methods.unique(exportName(opName))
+
+	var pathParams, queryParams, headerParams []*parameter
+	for _, p := range g.resolveParameters(item.Parameters, op.Parameters) {
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		case "header":
+			headerParams = append(headerParams, p)
+		}
+	}
+	sort.SliceStable(pathParams, func(i, j int) bool {
+		return strings.Index(path, "{"+pathParams[i].Name+"}") < strings.Index(path, "{"+pathParams[j].Name+"}")
+	})
+
+	args := []string{"ctx context.Context"}
+	argNames := names{}
+	pathArgs := make([]string, len(pathParams))
+	for i, p := range pathParams {
+		pathArgs[i] = argNames.unique(argName(p.Name))
+		args = append(args, pathArgs[i]+" "+g.goType(p.Schema, name+exportName(p.Name)))
+	}
+
+	paramsType := ""
+	headerFields := make([]string, len(headerParams))
+	if len(queryParams)+len(headerParams) > 0 {
+		paramsType = g.idents.unique(name + "Params")
+		headerFields = g.declareParams(paramsType, name, queryParams, headerParams)
+		args = append(args, "params *"+paramsType)
+	}
+
+	bodyType, contentType, bodyJSON := "", "", false
+	if rb := g.resolveRequestBody(op.RequestBody); rb != nil && len(rb.Content) > 0 {
+		var s *schema
+		contentType, s, bodyJSON = pickMedia(rb.Content)
+		if bodyJSON {
+			bodyType = g.goType(s, name+"Body")
+			if !rb.Required && !g.nilable(bodyType) {
+				bodyType = "*" + bodyType
+			}
+		} else {
+			bodyType = "[]byte"
+		}
+		args = append(args, "body "+bodyType)
+	}
+
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	resultType, resultRaw, accept := "", false, ""
+	for _, code := range codes {
+		resp := g.resolveResponse(op.Responses[code])
+		if !strings.HasPrefix(code, "2") || resp == nil || len(resp.Content) == 0 {
+			continue
+		}
+		var s *schema
+		var isJSON bool
+		accept, s, isJSON = pickMedia(resp.Content)
+		if isJSON {
+			resultType = g.goType(s, name+"Response")
+		} else {
+			resultType, resultRaw = "[]byte", true
+		}
+		break
+	}
+	returns, zero := "error", ""
+	if resultType != "" {
+		returnType := resultType
+		if !g.nilable(returnType) {
+			returnType = "*" + returnType
+		}
+		returns, zero = "("+returnType+", error)", "nil, "
+	}
+
+	if doc := strings.TrimSpace(op.Summary + "\n\n" + op.Description); doc != "" {
+		writeDoc(b, "", name, doc)
+		fmt.Fprintf(b, "//\n// %s %s\n", strings.ToUpper(method), path)
+	} else {
+		fmt.Fprintf(b, "// %s sends %s %s\n", name, strings.ToUpper(method), path)
+	}
+	if op.Deprecated {
+		b.WriteString("//\n// Deprecated: the operation is deprecated by the API\n")
+	}
+	fmt.Fprintf(b, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+
+	fmt.Fprintf(b, "\tpath := %q\n", path)
+	for i, p := range pathParams {
+		fmt.Fprintf(b, "\tpath = strings.Replace(path, %q, pathParam(%s), 1)\n", "{"+p.Name+"}", pathArgs[i])
+	}
+
+	headersArg := "nil"
+	if len(headerParams) > 0 {
+		headersArg = "headers"
+		b.WriteString("\theaders := map[string]string{}\n\tif params != nil {\n")
+		for i, p := range headerParams {
+			field := "params." + headerFields[i]
+			if p.Required {
+				fmt.Fprintf(b, "\t\theaders[%q] = headerValue(%s)\n", p.Name, field)
+			} else {
+				fmt.Fprintf(b, "\t\tif %s != nil {\n\t\t\theaders[%q] = headerValue(%s)\n\t\t}\n", field, p.Name, derefIfPointer(field, g.paramFieldType(p, name)))
+			}
+		}
+		b.WriteString("\t}\n")
+	}
+
+	paramsArg := "nil"
+	if paramsType != "" {
+		paramsArg = "params"
+	}
+	payloadArg := "nil"
+	switch {
+	case bodyType == "":
+	case !bodyJSON:
+		payloadArg = "body"
+	case g.nilable(bodyType):
+		payloadArg = "payload"
+		fmt.Fprintf(b, "\tvar payload []byte\n\tif body != nil {\n\t\tvar err error\n\t\tif payload, err = json.Marshal(body); err != nil {\n\t\t\treturn %serr\n\t\t}\n\t}\n", zero)
+	default:
+		payloadArg = "payload"
+		fmt.Fprintf(b, "\tpayload, err := json.Marshal(body)\n\tif err != nil {\n\t\treturn %serr\n\t}\n", zero)
+	}
+
+	fmt.Fprintf(b, "\tresp, err := c.send(ctx, http.Method%s, path, %s, %s, %q, %q, %s)\n", method, paramsArg, headersArg, contentType, accept, payloadArg)
+	fmt.Fprintf(b, "\tif err != nil {\n\t\treturn %serr\n\t}\n", zero)
+	fmt.Fprintf(b, "\tif err := checkStatus(resp, %s); err != nil {\n\t\treturn %serr\n\t}\n", g.errorPayloads(name, codes, op), zero)
+
+	switch {
+	case resultType == "":
+		b.WriteString("\treturn nil\n")
+	case resultRaw:
+		b.WriteString("\treturn resp.Body, nil\n")
+	case g.nilable(resultType):
+		fmt.Fprintf(b, "\tvar result %s\n\tif err := decodeJSON(resp, &result); err != nil {\n\t\treturn nil, err\n\t}\n\treturn result, nil\n", resultType)
+	default:
+		fmt.Fprintf(b, "\tresult := new(%s)\n\tif err := decodeJSON(resp, result); err != nil {\n\t\treturn nil, err\n\t}\n\treturn result, nil\n", resultType)
+	}
+	b.WriteString("}\n\n")
+}
+
+func (g *generator) paramFieldType(p *parameter, opName string) string {
+	typ := g.goType(p.Schema, opName+exportName(p.Name))
+	if !p.Required && !g.nilable(typ) {
+		typ = "*" + typ
+	}
+	return typ
+}
+
+func derefIfPointer(expr, typ string) string {
+	if strings.HasPrefix(typ, "*") {
+		return "*" + expr
+	}
+	return expr
+}
+
+// declareParams declares the struct of the query and header parameters of an operation
+// and returns the fields of the header parameters
+func (g *generator) declareParams(typeName, opName string, queryParams, headerParams []*parameter) []string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s are the parameters of %s\n", typeName, opName)
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+
+	fields := names{}
+	for _, p := range queryParams {
+		field := fields.unique(exportName(p.Name))
+		tag := p.Name
+		if !p.Required {
+			tag += ",omitempty"
+		}
+		writeDoc(&b, "\t", field, p.Description)
+		fmt.Fprintf(&b, "\t%s %s `url:%q`\n", field, g.paramFieldType(p, opName), tag)
+	}
+
+	headerFields := make([]string, len(headerParams))
+	for i, p := range headerParams {
+		headerFields[i] = fields.unique(exportName(p.Name))
+		writeDoc(&b, "\t", headerFields[i], p.Description)
+		fmt.Fprintf(&b, "\t%s %s `url:\"-\"`\n", headerFields[i], g.paramFieldType(p, opName))
+	}
+
+	b.WriteString("}\n\n")
+	g.decls.Write(b.Bytes())
+	return headerFields
+}
+
+// errorPayloads returns the function giving the value decoding the body of an error status
+func (g *generator) errorPayloads(opName string, codes []string, op *operation) string {
+	var cases []string
+	hasDefault := false
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			continue
+		}
+		resp := g.resolveResponse(op.Responses[code])
+		if resp == nil {
+			continue
+		}
+		_, s, isJSON := pickMedia(resp.Content)
+		if !isJSON {
+			continue
+		}
+		typ := g.goType(s, opName+"Error"+code)
+
+		var cond string
+		switch {
+		case code == "default":
+			cond, hasDefault = "default", true
+		case len(code) == 3 && strings.HasSuffix(strings.ToUpper(code), "XX"):
+			class, err := strconv.Atoi(code[:1])
+			if err != nil {
+				g.fail(fmt.Errorf("invalid response code %q", code))
+				continue
+			}
+			cond = fmt.Sprintf("case status >= %d && status < %d", class*100, class*100+100)
+		default:
+			if _, err := strconv.Atoi(code); err != nil {
+				g.fail(fmt.Errorf("invalid response code %q", code))
+				continue
+			}
+			cond = "case status == " + code
+		}
+		cases = append(cases, fmt.Sprintf("\t\t%s:\n\t\t\treturn new(%s)\n", cond, typ))
+	}
+	if len(cases) == 0 {
+		return "nil"
+	}
+
+	var b strings.Builder
+	b.WriteString("func(status int) interface{} {\n\t\tswitch {\n")
+	for _, c := range cases {
+		b.WriteString(c)
+	}
+	b.WriteString("\t\t}\n")
+	if !hasDefault {
+		b.WriteString("\t\treturn nil\n")
+	}
+	b.WriteString("\t}")
+	return b.String()
+}
+
+// writeDoc writes the description of name as a comment, nothing when empty
+func writeDoc(b *bytes.Buffer, indent, name, description string) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return
+	}
+	for i, line := range strings.Split(description, "\n") {
+		if i == 0 {
+			line = name + " " + line
+		}
+		fmt.Fprintf(b, "%s// %s\n", indent, strings.TrimRight(line, " \t"))
+	}
+}
@@ -0,0 +1,75 @@
+// Command openapi-gen generates a typed Go client from an OpenAPI 3 document in JSON.
+//
+// The client sends its calls through a request.Client and encodes the query parameters
+// with query.Values. Each operation becomes a method taking its path parameters, a struct
+// of its query and header parameters and its request body, and returning its 2xx response
+// decoded. Other statuses are returned as an *APIError whose Payload holds the body decoded
+// into the schema declared for the status.
+//
+// Usage:
+//
+//	openapi-gen -spec petstore.json -package petstore -o petstore/client.go
+//
+// or from a go:generate directive:
+//
+//	//go:generate go run github.com/atomgunlk/golang-common/cmd/openapi-gen -spec petstore.json -package petstore -o client.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	specPath := flag.String("spec", "", "path of the OpenAPI 3 document, in JSON")
+	pkg := flag.String("package", "", "package name of the generated file, defaults to the output directory name")
+	output := flag.String("o", "", "path of the generated file, defaults to stdout")
+	flag.Parse()
+
+	if err := run(*specPath, *pkg, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "openapi-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, pkg, output string) error {
+	if specPath == "" {
+		return fmt.Errorf("missing -spec")
+	}
+	if pkg == "" {
+		if output == "" {
+			return fmt.Errorf("missing -package")
+		}
+		abs, err := filepath.Abs(filepath.Dir(output))
+		if err != nil {
+			return err
+		}
+		pkg = strings.ToLower(strings.ReplaceAll(filepath.Base(abs), "-", ""))
+	}
+
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", specPath, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return fmt.Errorf("%s: unsupported openapi version %q", specPath, doc.OpenAPI)
+	}
+
+	src, err := generate(&doc, pkg, filepath.Base(specPath))
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(output, src, 0o644)
+}
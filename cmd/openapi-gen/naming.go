@@ -0,0 +1,114 @@
+package main
+
+import (
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var initialisms = map[string]bool{
+	"API": true, "CPU": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "TLS": true, "TTL": true,
+	"UI": true, "UID": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// reservedArgs are the identifiers used by the generated methods, imported packages included
+var reservedArgs = map[string]bool{
+	"body": true, "c": true, "ctx": true, "err": true, "headers": true,
+	"params": true, "path": true, "payload": true, "resp": true, "result": true,
+	"bytes": true, "context": true, "fmt": true, "http": true, "json": true,
+	"query": true, "request": true, "strings": true, "time": true, "url": true,
+}
+
+// splitWords splits an identifier on separators and case changes, HTTPServer gives HTTP and Server
+func splitWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	return words
+}
+
+func exportWord(w string) string {
+	if up := strings.ToUpper(w); initialisms[up] {
+		return up
+	}
+	runes := []rune(strings.ToLower(w))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// exportName turns a name of the document into an exported Go identifier
+func exportName(s string) string {
+	var b strings.Builder
+	for _, w := range splitWords(s) {
+		b.WriteString(exportWord(w))
+	}
+
+	name := b.String()
+	if name == "" {
+		return "X"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+// argName turns a name of the document into an unexported Go identifier usable as an argument
+func argName(s string) string {
+	words := splitWords(s)
+	if len(words) == 0 {
+		return "arg"
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(words[0]))
+	for _, w := range words[1:] {
+		b.WriteString(exportWord(w))
+	}
+
+	name := b.String()
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "n" + name
+	}
+	if token.IsKeyword(name) || reservedArgs[name] {
+		name += "Param"
+	}
+	return name
+}
+
+// names hands out unique identifiers within a scope
+type names map[string]bool
+
+func (n names) unique(name string) string {
+	candidate := name
+	for i := 2; n[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	n[candidate] = true
+	return candidate
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// document is the subset of an OpenAPI 3 document used by the generator
+type document struct {
+	OpenAPI    string             `json:"openapi"`
+	Info       info               `json:"info"`
+	Servers    []server           `json:"servers"`
+	Paths      ordered[*pathItem] `json:"paths"`
+	Components components         `json:"components"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type components struct {
+	Schemas       ordered[*schema]        `json:"schemas"`
+	Parameters    map[string]*parameter   `json:"parameters"`
+	RequestBodies map[string]*requestBody `json:"requestBodies"`
+	Responses     map[string]*response    `json:"responses"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Options    *operation   `json:"options"`
+	Head       *operation   `json:"head"`
+	Patch      *operation   `json:"patch"`
+	Trace      *operation   `json:"trace"`
+}
+
+// operations returns the operations of the path by http method, in a stable order
+func (p *pathItem) operations() ([]string, []*operation) {
+	methods := []string{"Get", "Put", "Post", "Delete", "Options", "Head", "Patch", "Trace"}
+	all := []*operation{p.Get, p.Put, p.Post, p.Delete, p.Options, p.Head, p.Patch, p.Trace}
+
+	var names []string
+	var ops []*operation
+	for i, op := range all {
+		if op != nil {
+			names = append(names, methods[i])
+			ops = append(ops, op)
+		}
+	}
+	return names, ops
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Deprecated  bool                 `json:"deprecated"`
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string           `json:"$ref"`
+	Type                 schemaType       `json:"type"`
+	Format               string           `json:"format"`
+	Description          string           `json:"description"`
+	Enum                 []interface{}    `json:"enum"`
+	Nullable             bool             `json:"nullable"`
+	Properties           ordered[*schema] `json:"properties"`
+	Required             []string         `json:"required"`
+	Items                *schema          `json:"items"`
+	AdditionalProperties *schema          `json:"additionalProperties"`
+	AllOf                []*schema        `json:"allOf"`
+	OneOf                []*schema        `json:"oneOf"`
+	AnyOf                []*schema        `json:"anyOf"`
+}
+
+// UnmarshalJSON accepts the boolean form of additionalProperties
+func (s *schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true", "false":
+		*s = schema{}
+		return nil
+	}
+
+	type plain schema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+func (s *schema) isRequired(name string) bool {
+	for _, required := range s.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaType is the type of a schema, OpenAPI 3.1 allows a list including "null"
+type schemaType struct {
+	name     string
+	nullable bool
+}
+
+func (t *schemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		t.name = single
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid schema type %s", data)
+	}
+	for _, name := range list {
+		if name == "null" {
+			t.nullable = true
+		} else if t.name == "" {
+			t.name = name
+		}
+	}
+	return nil
+}
+
+// ordered is a JSON object keeping the order of its keys
+type ordered[T any] struct {
+	keys   []string
+	values map[string]T
+}
+
+func (o *ordered[T]) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected an object, got %v", tok)
+	}
+
+	o.values = make(map[string]T)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var value T
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if _, dup := o.values[key]; !dup {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// refName returns the name of a local reference such as #/components/schemas/Pet
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported $ref %q", ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
@@ -5,9 +5,10 @@ go 1.19
 require (
 	github.com/hashicorp/go-retryablehttp v0.7.1
 	github.com/sirupsen/logrus v1.9.0
+	golang.org/x/net v0.31.0
 )
 
 require (
 	github.com/hashicorp/go-cleanhttp v0.5.1 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/sys v0.27.0 // indirect
 )
@@ -0,0 +1,145 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultBatchConcurrency = 10
+
+// BatchRequest describes one call of a batch
+type BatchRequest struct {
+	Method string // GET when empty
+	URL    string
+	Opts   SendOptions
+	Body   []byte
+}
+
+// BatchResult holds the outcome of the request at the same index
+type BatchResult struct {
+	Response *Response
+	Err      error
+}
+
+// BatchOptions configures a batch execution
+type BatchOptions struct {
+	// Concurrency is the max number of calls in flight, 10 by default
+	Concurrency int
+	// Rate is the max number of calls started per second, unlimited when zero
+	Rate float64
+	// FailFast stops the batch on the first error and cancels the calls in flight,
+	// otherwise every call is executed and the errors are collected
+	FailFast bool
+}
+
+// BatchError lists the failed calls of a batch executed without FailFast
+type BatchError struct {
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	msgs := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		msgs = append(msgs, fmt.Sprintf("[%d]: %v", i, e.Errors[i]))
+	}
+	return fmt.Sprintf("batch: %d request(s) failed: %s", len(indexes), strings.Join(msgs, "; "))
+}
+
+// ExecuteBatch sends the requests through c and returns their results in the input order.
+// With FailFast the first error is returned, otherwise a *BatchError lists the failed calls.
+// Calls not started when ctx is done carry the context error
+func ExecuteBatch(ctx context.Context, c Client, reqs []BatchRequest, opts BatchOptions) ([]BatchResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var ticker *time.Ticker
+	if opts.Rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+	}
+
+	results := make([]BatchResult, len(reqs))
+	sem := make(chan struct{}, concurrency)
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+
+	started := 0
+	for i, r := range reqs {
+		if i > 0 && ticker != nil {
+			select {
+			case <-ticker.C:
+			case <-batchCtx.Done():
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-batchCtx.Done():
+		}
+		if batchCtx.Err() != nil {
+			break
+		}
+
+		started++
+		wg.Add(1)
+		go func(i int, r BatchRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			method := r.Method
+			if method == "" {
+				method = http.MethodGet
+			}
+			resp, err := SendWithContext(batchCtx, c, method, r.URL, r.Opts, r.Body)
+			results[i] = BatchResult{Response: resp, Err: err}
+			if err != nil && opts.FailFast {
+				failOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	for i := started; i < len(reqs); i++ {
+		results[i].Err = batchCtx.Err()
+	}
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+
+	batchErr := &BatchError{Errors: make(map[int]error)}
+	for i, result := range results {
+		if result.Err != nil {
+			batchErr.Errors[i] = result.Err
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return results, batchErr
+	}
+
+	return results, nil
+}
@@ -0,0 +1,100 @@
+package request
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBudgetWindow       = 10 * time.Second
+	defaultBudgetMinPerSecond = 10
+)
+
+// RetryBudgetOptions configures the client retry budget
+type RetryBudgetOptions struct {
+	// Ratio is the share of the recent successful requests which may be retried, e.g. 0.2 for 20%
+	Ratio float64
+	// MinPerSecond is the number of retries per second always allowed, 10 by default
+	MinPerSecond int
+	// Window is the period of the recent requests, 10s by default
+	Window time.Duration
+}
+
+// WithRetryBudget caps the retries of the whole client to MinPerSecond plus Ratio of the
+// successful requests of the recent Window, so retries stop amplifying the traffic during
+// a widespread failure. Suppressed retries are reported as MetricRetriesSuppressed
+func WithRetryBudget(opts RetryBudgetOptions) OptionClient {
+	if opts.MinPerSecond <= 0 {
+		opts.MinPerSecond = defaultBudgetMinPerSecond
+	}
+	if opts.Window < time.Second {
+		opts.Window = defaultBudgetWindow
+	}
+	return func(r *ClientOptions) {
+		r.retryBudget = newRetryBudget(opts)
+	}
+}
+
+type budgetBucket struct {
+	second    int64
+	successes int
+	retries   int
+}
+
+// retryBudget counts successes and retries in one second buckets over the window
+type retryBudget struct {
+	opts RetryBudgetOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	buckets []budgetBucket
+}
+
+func newRetryBudget(opts RetryBudgetOptions) *retryBudget {
+	return &retryBudget{
+		opts:    opts,
+		now:     time.Now,
+		buckets: make([]budgetBucket, int(opts.Window/time.Second)),
+	}
+}
+
+// bucket returns the bucket of the current second, reset when it is reused
+func (b *retryBudget) bucket() *budgetBucket {
+	second := b.now().Unix()
+	bucket := &b.buckets[second%int64(len(b.buckets))]
+	if bucket.second != second {
+		*bucket = budgetBucket{second: second}
+	}
+	return bucket
+}
+
+// deposit records a successful request
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket().successes++
+}
+
+// withdraw records a retry and reports whether the budget allows it
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := b.bucket()
+	oldest := current.second - int64(len(b.buckets)) + 1
+	successes, retries := 0, 0
+	for _, bucket := range b.buckets {
+		if bucket.second >= oldest {
+			successes += bucket.successes
+			retries += bucket.retries
+		}
+	}
+
+	allowed := float64(b.opts.MinPerSecond*len(b.buckets)) + b.opts.Ratio*float64(successes)
+	if float64(retries) >= allowed {
+		return false
+	}
+	current.retries++
+
+	return true
+}
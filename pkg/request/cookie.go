@@ -0,0 +1,199 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// NewCookieJar returns an in memory cookie jar aware of the public suffix list,
+// so a site cannot set cookies for a whole suffix such as co.uk
+func NewCookieJar() http.CookieJar {
+	// cookiejar.New only fails on invalid options
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return jar
+}
+
+// WithCookieJar enables an in memory public suffix aware cookie jar
+func WithCookieJar() OptionClient {
+	return WithJar(NewCookieJar())
+}
+
+// WithJar sets the cookie jar of the client, e.g. a PersistentJar
+func WithJar(jar http.CookieJar) OptionClient {
+	return func(r *ClientOptions) {
+		r.HTTPClient.Jar = jar
+	}
+}
+
+// persistedCookie is a cookie with the URL it was received from, so it can be set again on load
+type persistedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Path     string        `json:"path,omitempty"`
+	Domain   string        `json:"domain,omitempty"`
+	Expires  time.Time     `json:"expires,omitempty"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+func (c persistedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// PersistentJar is a public suffix aware cookie jar saved to a file, session cookies included,
+// so a scripted session survives a restart. Call Save to write the cookies to disk
+type PersistentJar struct {
+	path string
+	jar  http.CookieJar
+
+	mu      sync.Mutex
+	cookies map[string]persistedCookie
+}
+
+// NewPersistentJar returns a jar loaded from path when the file exists
+func NewPersistentJar(path string) (*PersistentJar, error) {
+	j := &PersistentJar{
+		path:    path,
+		jar:     NewCookieJar(),
+		cookies: make(map[string]persistedCookie),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cookies []persistedCookie
+	if err := json.Unmarshal(b, &cookies); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, c := range cookies {
+		if c.expired(now) {
+			continue
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}})
+	}
+
+	return j, nil
+}
+
+// SetCookies satisfies the http.CookieJar interface
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		pc := persistedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}
+
+		key := cookieKey(u, c)
+		if c.MaxAge < 0 || pc.expired(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = pc
+	}
+}
+
+// Cookies satisfies the http.CookieJar interface
+func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the unexpired cookies to the jar file, replacing it atomically
+func (j *PersistentJar) Save() error {
+	j.mu.Lock()
+	now := time.Now()
+	cookies := make([]persistedCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if !c.expired(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	j.mu.Unlock()
+
+	b, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), j.path)
+}
+
+// cookieKey identifies a cookie by name, domain and path as the jar does
+func cookieKey(u *url.URL, c *http.Cookie) string {
+	domain := c.Domain
+	if domain == "" {
+		domain = u.Hostname()
+	}
+	cookiePath := c.Path
+	if cookiePath == "" || cookiePath[0] != '/' {
+		cookiePath = path.Dir(u.Path)
+		if u.Path == "" || u.Path[0] != '/' {
+			cookiePath = "/"
+		}
+	}
+
+	return c.Name + ";" + domain + ";" + cookiePath
+}
@@ -0,0 +1,87 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultDeadlineHeader carries the time left to the callee, in milliseconds
+const DefaultDeadlineHeader = "X-Request-Timeout"
+
+// ErrDeadlineExhausted is returned for an attempt with no time left for the callee, it is not retried
+var ErrDeadlineExhausted = fmt.Errorf("deadline: no time left for the callee: %w", context.DeadlineExceeded)
+
+// WithDeadlinePropagation sends the time left before the context deadline, minus margin,
+// in the header of every attempt, DefaultDeadlineHeader when empty.
+// An attempt with no time left after the margin fails with ErrDeadlineExhausted without being sent
+func WithDeadlinePropagation(header string, margin time.Duration) OptionClient {
+	if header == "" {
+		header = DefaultDeadlineHeader
+	}
+	return WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &deadlineTransport{header: header, margin: margin, next: next}
+	})
+}
+
+type deadlineTransport struct {
+	header string
+	margin time.Duration
+	next   http.RoundTripper
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	left := time.Until(deadline) - t.margin
+	if left < time.Millisecond {
+		closeRequestBody(req)
+		return nil, ErrDeadlineExhausted
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, strconv.FormatInt(left.Milliseconds(), 10))
+
+	return t.next.RoundTrip(req)
+}
+
+// HeaderDeadline returns the timeout carried by the header of r, DefaultDeadlineHeader when empty
+func HeaderDeadline(r *http.Request, header string) (time.Duration, bool) {
+	if header == "" {
+		header = DefaultDeadlineHeader
+	}
+	ms, err := strconv.ParseInt(r.Header.Get(header), 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// ContextWithHeaderDeadline returns a copy of the request context bounded by the timeout
+// of the header, the request context when the header is missing or invalid
+func ContextWithHeaderDeadline(r *http.Request, header string) (context.Context, context.CancelFunc) {
+	timeout, ok := HeaderDeadline(r, header)
+	if !ok {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// DeadlineMiddleware bounds the context of the requests by the timeout of their header,
+// DefaultDeadlineHeader when empty. A request with no time left gets 504 Gateway Timeout
+func DeadlineMiddleware(header string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := ContextWithHeaderDeadline(r, header)
+		defer cancel()
+		if ctx.Err() != nil {
+			http.Error(w, "deadline exceeded", http.StatusGatewayTimeout)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
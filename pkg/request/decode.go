@@ -0,0 +1,147 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DecodeOptions are passed to the decoders
+type DecodeOptions struct {
+	// Strict rejects unknown fields, honored by the JSON decoder
+	Strict bool
+}
+
+// Decoder decodes a response body into v
+type Decoder func(body []byte, v interface{}, opts DecodeOptions) error
+
+// UnsupportedMediaTypeError is returned by Response.Decode when no decoder
+// is registered for the response content type
+type UnsupportedMediaTypeError struct {
+	ContentType string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	if e.ContentType == "" {
+		return "decode: response has no Content-Type"
+	}
+	return fmt.Sprintf("decode: no decoder registered for Content-Type %q", e.ContentType)
+}
+
+var decoders = struct {
+	sync.RWMutex
+	m map[string]Decoder
+}{
+	m: map[string]Decoder{
+		"application/json": decodeJSON,
+		"application/xml":  decodeXML,
+		"text/xml":         decodeXML,
+		FormContentType:    decodeForm,
+	},
+}
+
+// RegisterDecoder registers the decoder of a media type, e.g. "application/msgpack",
+// replacing the existing one
+func RegisterDecoder(mediaType string, d Decoder) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	decoders.m[strings.ToLower(mediaType)] = d
+}
+
+// Decode decodes the body into v with the decoder registered for the response content type.
+// Media types with a +json or +xml suffix fall back to the JSON or XML decoder
+func (r *Response) Decode(v interface{}) error {
+	return r.decode(v, DecodeOptions{})
+}
+
+// DecodeStrict decodes like Decode but rejects unknown JSON fields
+func (r *Response) DecodeStrict(v interface{}) error {
+	return r.decode(v, DecodeOptions{Strict: true})
+}
+
+func (r *Response) decode(v interface{}, opts DecodeOptions) error {
+	contentType := r.Header.Get("Content-Type")
+	decoder := lookupDecoder(contentType)
+	if decoder == nil {
+		return &UnsupportedMediaTypeError{ContentType: contentType}
+	}
+	if len(bytes.TrimSpace(r.Body)) == 0 {
+		return nil
+	}
+
+	return decoder(r.Body, v, opts)
+}
+
+// lookupDecoder returns the decoder of the media type, or of its structured syntax suffix
+func lookupDecoder(contentType string) Decoder {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	decoders.RLock()
+	defer decoders.RUnlock()
+	if decoder, ok := decoders.m[mediaType]; ok {
+		return decoder
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if decoder, ok := decoders.m["application/"+mediaType[i+1:]]; ok {
+			return decoder
+		}
+	}
+
+	return nil
+}
+
+func decodeJSON(body []byte, v interface{}, opts DecodeOptions) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if opts.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("decode: json: %w", err)
+	}
+
+	return nil
+}
+
+func decodeXML(body []byte, v interface{}, _ DecodeOptions) error {
+	if err := xml.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode: xml: %w", err)
+	}
+
+	return nil
+}
+
+// decodeForm decodes into *url.Values, *map[string][]string or *map[string]string
+func decodeForm(body []byte, v interface{}, _ DecodeOptions) error {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return fmt.Errorf("decode: form: %w", err)
+	}
+
+	switch dst := v.(type) {
+	case *url.Values:
+		*dst = values
+	case *map[string][]string:
+		*dst = values
+	case *map[string]string:
+		m := make(map[string]string, len(values))
+		for key := range values {
+			m[key] = values.Get(key)
+		}
+		*dst = m
+	default:
+		return fmt.Errorf("decode: form: unsupported destination %T", v)
+	}
+
+	return nil
+}
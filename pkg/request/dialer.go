@@ -0,0 +1,81 @@
+package request
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DialContextFunc dials a connection, as http.Transport.DialContext
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+var defaultDialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+}
+
+// WithUnixSocket sends the requests for host to the Unix domain socket at socketPath,
+// e.g. WithUnixSocket("docker", "/var/run/docker.sock") then Get("http://docker/v1.41/info", nil)
+func WithUnixSocket(host, socketPath string) OptionClient {
+	return func(r *ClientOptions) {
+		if r.unixSockets == nil {
+			r.unixSockets = make(map[string]string)
+		}
+		r.unixSockets[host] = socketPath
+	}
+}
+
+// WithDialContext sets the function dialing the TCP connections
+func WithDialContext(dial DialContextFunc) OptionClient {
+	return func(r *ClientOptions) {
+		r.dialContext = dial
+	}
+}
+
+// WithResolve statically resolves hosts to the mapped IP, or IP:port, instead of using DNS.
+// The TLS server name is still the requested host
+func WithResolve(hosts map[string]string) OptionClient {
+	return func(r *ClientOptions) {
+		if r.resolve == nil {
+			r.resolve = make(map[string]string)
+		}
+		for host, ip := range hosts {
+			r.resolve[host] = ip
+		}
+	}
+}
+
+// applyDialer sets the dialer on the transport when a dial option is used.
+// It has no effect when an option replaced the transport with another RoundTripper
+func (o *ClientOptions) applyDialer() {
+	if o.dialContext == nil && len(o.unixSockets) == 0 && len(o.resolve) == 0 {
+		return
+	}
+	tr, ok := o.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+
+	dial := o.dialContext
+	if dial == nil {
+		dial = defaultDialer.DialContext
+	}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		if socketPath, ok := o.unixSockets[host]; ok {
+			return defaultDialer.DialContext(ctx, "unix", socketPath)
+		}
+		if target, ok := o.resolve[host]; ok {
+			if _, _, err := net.SplitHostPort(target); err == nil {
+				addr = target
+			} else {
+				addr = net.JoinHostPort(target, port)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
@@ -0,0 +1,350 @@
+package request
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithDigestAuth authenticates the requests with HTTP Digest authentication (RFC 7616).
+// The 401 challenge is answered with MD5 or SHA-256 and qop=auth, the nonce is then
+// reused with an incremented nonce count until the server asks for a new one.
+// Challenges are kept per scheme and host, credentials are only sent to the host which asked for them
+func WithDigestAuth(username, password string) OptionClient {
+	return WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &digestTransport{username: username, password: password, next: next}
+	})
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+	stale     bool
+	// nc is the count of requests sent with the nonce
+	nc uint32
+}
+
+type digestTransport struct {
+	username string
+	password string
+	next     http.RoundTripper
+
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+// challengeKey is the protection space of the request, its scheme and host
+func challengeKey(req *http.Request) string {
+	return req.URL.Scheme + "://" + strings.ToLower(req.URL.Host)
+}
+
+// RoundTrip satisfies the http.RoundTripper interface
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body is streamed on the first attempt and only replayed when a challenge is answered
+	first := req.Clone(req.Context())
+	var sent *digestBody
+	if first.Body != nil && first.Body != http.NoBody && first.GetBody == nil {
+		sent = &digestBody{body: first.Body, done: make(chan struct{})}
+		first.Body = sent
+		defer sent.release()
+	}
+	authorized, err := t.authorize(first)
+	if err != nil {
+		closeRequestBody(first)
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(first)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := parseDigestChallenges(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil {
+		return resp, nil
+	}
+	t.mu.Lock()
+	if t.challenges == nil {
+		t.challenges = make(map[string]*digestChallenge)
+	}
+	t.challenges[challengeKey(req)] = challenge
+	t.mu.Unlock()
+	if authorized && !challenge.stale {
+		// the credentials were rejected with the current nonce
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	switch {
+	case sent != nil:
+		retry.Body = sent.replay()
+	case req.GetBody != nil && req.Body != nil && req.Body != http.NoBody:
+		body, err := req.GetBody()
+		if err != nil {
+			drainBody(resp.Body)
+			return nil, err
+		}
+		retry.Body = body
+	}
+	drainBody(resp.Body)
+	if _, err := t.authorize(retry); err != nil {
+		closeRequestBody(retry)
+		return nil, err
+	}
+
+	return t.next.RoundTrip(retry)
+}
+
+// digestBody records the body sent on the first attempt so it can be replayed after a challenge.
+// The original body is kept open until RoundTrip knows whether it is replayed
+type digestBody struct {
+	body io.ReadCloser
+	done chan struct{}
+
+	mu      sync.Mutex
+	sent    bytes.Buffer
+	eof     bool
+	closed  bool
+	decided bool
+	keep    bool
+}
+
+func (b *digestBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	b.sent.Write(p[:n])
+	if err == io.EOF {
+		b.eof = true
+	}
+	b.mu.Unlock()
+
+	return n, err
+}
+
+func (b *digestBody) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	release := b.eof || (b.decided && !b.keep)
+	b.mu.Unlock()
+	close(b.done)
+	if release {
+		return b.body.Close()
+	}
+
+	return nil
+}
+
+// release closes the original body once the transport is done with it, unless it is replayed
+func (b *digestBody) release() {
+	b.mu.Lock()
+	if b.decided && b.keep {
+		b.mu.Unlock()
+		return
+	}
+	b.decided = true
+	closeNow := b.closed && !b.eof
+	b.mu.Unlock()
+	if closeNow {
+		b.body.Close()
+	}
+}
+
+// replay waits for the transport to be done with the body, then returns the bytes
+// already sent followed by the unread rest of the original body
+func (b *digestBody) replay() io.ReadCloser {
+	b.mu.Lock()
+	b.decided = true
+	b.keep = true
+	b.mu.Unlock()
+	<-b.done
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sent := bytes.NewReader(b.sent.Bytes())
+	if b.eof {
+		return io.NopCloser(sent)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(sent, b.body), b.body}
+}
+
+// authorize sets the Authorization header when a challenge of the request host is known
+func (t *digestTransport) authorize(req *http.Request) (bool, error) {
+	t.mu.Lock()
+	challenge := t.challenges[challengeKey(req)]
+	if challenge == nil {
+		t.mu.Unlock()
+		return false, nil
+	}
+	challenge.nc++
+	nc := challenge.nc
+	t.mu.Unlock()
+
+	cnonceBytes := make([]byte, 16)
+	if _, err := rand.Read(cnonceBytes); err != nil {
+		return false, err
+	}
+	cnonce := hex.EncodeToString(cnonceBytes)
+
+	h := digestHash(challenge.algorithm)
+	username := t.username
+	if challenge.userhash {
+		username = h(t.username + ":" + challenge.realm)
+	}
+	ha1 := h(t.username + ":" + challenge.realm + ":" + t.password)
+	if strings.HasSuffix(strings.ToLower(challenge.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + challenge.nonce + ":" + cnonce)
+	}
+	uri := req.URL.RequestURI()
+	ha2 := h(req.Method + ":" + uri)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	params := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, challenge.realm),
+		fmt.Sprintf(`nonce="%s"`, challenge.nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+	}
+	if challenge.qop != "" {
+		response := h(ha1 + ":" + challenge.nonce + ":" + ncValue + ":" + cnonce + ":" + challenge.qop + ":" + ha2)
+		params = append(params,
+			fmt.Sprintf(`response="%s"`, response),
+			"qop="+challenge.qop,
+			"nc="+ncValue,
+			fmt.Sprintf(`cnonce="%s"`, cnonce),
+		)
+	} else {
+		params = append(params, fmt.Sprintf(`response="%s"`, h(ha1+":"+challenge.nonce+":"+ha2)))
+	}
+	if challenge.algorithm != "" {
+		params = append(params, "algorithm="+challenge.algorithm)
+	}
+	if challenge.opaque != "" {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, challenge.opaque))
+	}
+	if challenge.userhash {
+		params = append(params, "userhash=true")
+	}
+	req.Header.Set("Authorization", "Digest "+strings.Join(params, ", "))
+
+	return true, nil
+}
+
+// digestHash returns the hex encoded hash function of the algorithm, MD5 by default
+func digestHash(algorithm string) func(string) string {
+	var newHash func() hash.Hash = md5.New
+	if strings.HasPrefix(strings.ToUpper(algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	return func(s string) string {
+		h := newHash()
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// parseDigestChallenges returns the supported Digest challenge, SHA-256 being preferred over MD5
+func parseDigestChallenges(headers []string) *digestChallenge {
+	var selected *digestChallenge
+	for _, header := range headers {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		algorithm := params["algorithm"]
+		switch strings.ToUpper(algorithm) {
+		case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		default:
+			continue
+		}
+		qop := ""
+		for _, q := range strings.Split(params["qop"], ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if params["qop"] != "" && qop == "" {
+			// only auth-int is offered
+			continue
+		}
+
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: algorithm,
+			qop:       qop,
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+			stale:     strings.EqualFold(params["stale"], "true"),
+		}
+		if selected == nil || strings.HasPrefix(strings.ToUpper(algorithm), "SHA-256") {
+			selected = challenge
+		}
+	}
+
+	return selected
+}
+
+// parseAuthParams parses comma separated key=value pairs with optionally quoted values
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, found := strings.Cut(s, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			if i < len(rest) {
+				i++
+			}
+			s = rest[i:]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[key] = value.String()
+	}
+
+	return params
+}
+
+// drainBody reads a little of the body so the connection can be reused, then closes it
+func drainBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 4096))
+	body.Close()
+}
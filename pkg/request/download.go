@@ -0,0 +1,296 @@
+package request
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultDownloadAttempts = 5
+	partialFileSuffix       = ".part"
+	// validatorFileSuffix names the file keeping the ETag or Last-Modified of the partial file
+	validatorFileSuffix = ".validator"
+)
+
+// ErrChecksumMismatch is returned when the downloaded file does not match DownloadOptions.SHA256
+var ErrChecksumMismatch = errors.New("download: sha256 checksum mismatch")
+
+// DownloadOptions configures Download
+type DownloadOptions struct {
+	// SendOptions are the query params and headers of the download request
+	SendOptions SendOptions
+	// SHA256 is the expected hex encoded checksum of the file, not verified when empty
+	SHA256 string
+	// Progress is called as the file is written with the bytes written so far
+	// and the total size, -1 when unknown
+	Progress func(written, total int64)
+	// MaxAttempts is the number of tries, each one resuming where the previous stopped, 5 by default
+	MaxAttempts int
+}
+
+// download holds the state of a file download across its attempts
+type download struct {
+	file          *os.File
+	written       int64
+	total         int64
+	validator     string
+	validatorPath string
+}
+
+// Download streams targetURL into destPath. The content is written to destPath.part,
+// resumed with a Range request after a failure when the server supports it,
+// validated against Content-Length and the optional SHA-256, then renamed to destPath.
+// The validator of the content is kept in destPath.part.validator so a later run only resumes
+// the same version of the file, a partial file without validator is downloaded again
+func Download(ctx context.Context, c Client, targetURL, destPath string, opts DownloadOptions) error {
+	return streamingClient(c).download(ctx, targetURL, destPath, opts)
+}
+
+func (c client) download(ctx context.Context, targetURL, destPath string, opts DownloadOptions) error {
+	if c.debugEnable {
+		c.logger.WithFields(logrus.Fields{
+			"url":  targetURL,
+			"dest": destPath,
+		}).Debug("[Download]: http request")
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultDownloadAttempts
+	}
+
+	partPath := destPath + partialFileSuffix
+	file, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	written, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	dl := &download{file: file, written: written, total: -1, validatorPath: partPath + validatorFileSuffix}
+	if validator, err := os.ReadFile(dl.validatorPath); err == nil {
+		dl.validator = strings.TrimSpace(string(validator))
+	}
+	if dl.written > 0 && dl.validator == "" {
+		// the partial file cannot be matched with the remote one
+		if err := dl.restart(); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = c.downloadOnce(ctx, targetURL, dl, opts)
+		if err == nil {
+			break
+		}
+		var statusErr *downloadStatusError
+		if ctx.Err() != nil || errors.As(err, &statusErr) || attempt >= maxAttempts {
+			return err
+		}
+		if c.debugEnable {
+			c.logger.WithError(err).WithField("written", dl.written).Debug("[Download]: resuming download")
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if opts.SHA256 != "" {
+		if err := verifySHA256(file, opts.SHA256); err != nil {
+			file.Close()
+			os.Remove(partPath)
+			os.Remove(dl.validatorPath)
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(partPath, destPath); err != nil {
+		return err
+	}
+	os.Remove(dl.validatorPath)
+
+	return nil
+}
+
+// downloadStatusError reports a response status which cannot be resumed
+type downloadStatusError struct {
+	StatusCode int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("download: unexpected status %d", e.StatusCode)
+}
+
+// downloadOnce requests the remaining content and appends it to the partial file
+func (c client) downloadOnce(ctx context.Context, targetURL string, dl *download, opts DownloadOptions) error {
+	req, err := newRequest(ctx, http.MethodGet, targetURL, opts.SendOptions, nil)
+	if err != nil {
+		return err
+	}
+	if dl.written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", dl.written))
+		if dl.validator != "" {
+			req.Header.Set("If-Range", dl.validator)
+		}
+	}
+	req = req.WithContext(c.options.withCallState(req.Context(), req, opts.SendOptions))
+
+	resp, err := c.httpClient(req).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the whole content is sent, the server ignored the range or the file changed
+		if err := dl.restart(); err != nil {
+			return err
+		}
+		dl.total = -1
+		if resp.ContentLength >= 0 {
+			dl.total = resp.ContentLength
+		}
+	case http.StatusPartialContent:
+		start, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if start != dl.written {
+			return fmt.Errorf("download: range starts at %d instead of %d", start, dl.written)
+		}
+		dl.total = total
+		if total < 0 && resp.ContentLength >= 0 {
+			dl.total = dl.written + resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file does not match the remote one anymore
+		if err := dl.restart(); err != nil {
+			return err
+		}
+		return fmt.Errorf("download: %s", resp.Status)
+	default:
+		return &downloadStatusError{StatusCode: resp.StatusCode}
+	}
+
+	validator := resp.Header.Get("Last-Modified")
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		validator = etag
+	}
+	if err := dl.setValidator(validator); err != nil {
+		return err
+	}
+
+	writer := &progressWriter{w: dl.file, written: dl.written, total: dl.total, progress: opts.Progress}
+	_, err = io.Copy(writer, resp.Body)
+	dl.written = writer.written
+	if err != nil {
+		return err
+	}
+	if dl.total >= 0 && dl.written != dl.total {
+		return fmt.Errorf("download: got %d bytes, expected %d: %w", dl.written, dl.total, io.ErrUnexpectedEOF)
+	}
+
+	return nil
+}
+
+// restart truncates the partial file
+func (dl *download) restart() error {
+	if err := dl.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := dl.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dl.written = 0
+
+	return dl.setValidator("")
+}
+
+// setValidator keeps the validator of the partial file next to it
+func (dl *download) setValidator(validator string) error {
+	if validator == dl.validator {
+		return nil
+	}
+	dl.validator = validator
+	if validator == "" {
+		if err := os.Remove(dl.validatorPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return os.WriteFile(dl.validatorPath, []byte(validator), 0644)
+}
+
+// progressWriter reports the bytes written to the progress callback
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.progress != nil {
+		p.progress(p.written, p.total)
+	}
+
+	return n, err
+}
+
+// parseContentRange parses `bytes start-end/total`, total is -1 when unknown
+func parseContentRange(header string) (start, total int64, err error) {
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", header)
+	}
+	rng, size, found := strings.Cut(strings.TrimPrefix(header, "bytes "), "/")
+	if !found {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", header)
+	}
+	first, _, _ := strings.Cut(rng, "-")
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", header)
+	}
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("download: invalid Content-Range %q", header)
+		}
+	}
+
+	return start, total, nil
+}
+
+// verifySHA256 compares the checksum of the whole file with the expected hex value
+func verifySHA256(file *os.File, expected string) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), expected) {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
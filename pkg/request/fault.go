@@ -0,0 +1,207 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultKind is the misbehavior injected by a FaultRule
+type FaultKind int
+
+const (
+	// FaultLatency only delays the request by Latency
+	FaultLatency FaultKind = iota
+	// FaultReset fails the request with a connection reset error
+	FaultReset
+	// FaultTimeout fails the request with a timeout error
+	FaultTimeout
+	// FaultStatus answers with StatusCode, 503 when unset, without reaching the server
+	FaultStatus
+	// FaultTruncate cuts the response body after TruncateAfter bytes
+	FaultTruncate
+)
+
+// FaultRule injects a fault into the requests it matches
+type FaultRule struct {
+	// Host matches the request host, with or without port, any host when empty
+	Host string
+	// PathPrefix matches the beginning of the request path, any path when empty
+	PathPrefix string
+	// Probability, between 0 and 1, that a matching request gets the fault.
+	// Every matching request gets it when Probability is 0, i.e. unset
+	Probability float64
+
+	Kind FaultKind
+	// Latency is added before the fault, whatever its kind
+	Latency time.Duration
+	// StatusCode is the status of FaultStatus, 503 Service Unavailable when 0
+	StatusCode    int
+	TruncateAfter int64
+}
+
+func (r FaultRule) matches(req *http.Request) bool {
+	if r.Host != "" && r.Host != req.URL.Host && r.Host != req.URL.Hostname() {
+		return false
+	}
+	return strings.HasPrefix(req.URL.Path, r.PathPrefix)
+}
+
+// FaultTransport is a RoundTripper injecting the faults of its rules for chaos testing.
+// The first matching rule drawn by its probability applies, the other requests go through next
+type FaultTransport struct {
+	next  http.RoundTripper
+	rules []FaultRule
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewFaultTransport returns a FaultTransport wrapping next
+func NewFaultTransport(next http.RoundTripper, rules ...FaultRule) *FaultTransport {
+	return &FaultTransport{
+		next:  next,
+		rules: rules,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// WithFaultInjection injects the faults of rules into every attempt of the requests,
+// so retries and circuit breaking can be exercised in tests
+func WithFaultInjection(rules ...FaultRule) OptionClient {
+	return WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return NewFaultTransport(next, rules...)
+	})
+}
+
+// WithSeededFaultInjection is WithFaultInjection with the probability draws seeded by seed,
+// so a chaos test replays the same faults on every run
+func WithSeededFaultInjection(seed int64, rules ...FaultRule) OptionClient {
+	return WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		t := NewFaultTransport(next, rules...)
+		t.Seed(seed)
+		return t
+	})
+}
+
+// Seed makes the probability draws deterministic
+func (t *FaultTransport) Seed(seed int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rand = rand.New(rand.NewSource(seed))
+}
+
+func (t *FaultTransport) draw(probability float64) bool {
+	if probability <= 0 || probability >= 1 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rand.Float64() < probability
+}
+
+// RoundTrip satisfies the http.RoundTripper interface
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule, ok := t.match(req)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	if rule.Latency > 0 {
+		timer := time.NewTimer(rule.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	switch rule.Kind {
+	case FaultReset:
+		closeRequestBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	case FaultTimeout:
+		closeRequestBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: faultTimeoutError{}}
+	case FaultStatus:
+		closeRequestBody(req)
+		status := rule.StatusCode
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          io.NopCloser(bytes.NewReader(nil)),
+			ContentLength: 0,
+			Request:       req,
+		}, nil
+	case FaultTruncate:
+		resp, err := t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &truncatedBody{body: resp.Body, remaining: rule.TruncateAfter}
+		return resp, nil
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+func (t *FaultTransport) match(req *http.Request) (FaultRule, bool) {
+	for _, rule := range t.rules {
+		if rule.matches(req) && t.draw(rule.Probability) {
+			return rule, true
+		}
+	}
+
+	return FaultRule{}, false
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// faultTimeoutError is the net.Error of an injected timeout
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return "i/o timeout (injected)" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+
+// truncatedBody fails with io.ErrUnexpectedEOF once remaining bytes were read
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,35 @@
+package request
+
+import (
+	"github.com/atomgunlk/golang-common/pkg/querystring/query"
+)
+
+// FormContentType is the content type of url-encoded form bodies
+const FormContentType = "application/x-www-form-urlencoded"
+
+// EncodeForm encodes a struct with `url` tags into an url-encoded form body,
+// following the same rules as query strings (see query.Values)
+func EncodeForm(form interface{}) ([]byte, error) {
+	values, err := query.Values(form)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(values.Encode()), nil
+}
+
+// PostForm encodes form with EncodeForm and posts it through c as application/x-www-form-urlencoded
+//
+//	type Login struct {
+//		User     string `url:"user"`
+//		Password string `url:"password"`
+//	}
+//	resp, err := request.PostForm(client, url, nil, Login{User: "ec", Password: "secret"})
+func PostForm(c Client, targetURL string, opts SendOptions, form interface{}) (*Response, error) {
+	body, err := EncodeForm(form)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Post(targetURL, opts.SetContentType(FormContentType), body)
+}
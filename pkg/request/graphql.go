@@ -0,0 +1,177 @@
+package request
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const persistedQueryNotFound = "PersistedQueryNotFound"
+
+// GraphQLRequest is a GraphQL operation, Variables is marshalled to JSON
+// so it can be a struct or a map
+type GraphQLRequest struct {
+	Query         string
+	OperationName string
+	Variables     interface{}
+}
+
+// GraphQLLocation is a location of a GraphQL error in the query document
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLError is an entry of the GraphQL errors array
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return "graphql: " + e.Message
+	}
+
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("graphql: %s (path: %s)", e.Message, strings.Join(path, "."))
+}
+
+// GraphQLErrors is returned when the response carries a non empty errors array,
+// the data is still decoded as the errors may be partial
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// GraphQLClient sends queries and mutations through a Client
+type GraphQLClient struct {
+	// PersistedQueries sends the SHA-256 hash of the query instead of the query document,
+	// the document is sent only when the server does not know the hash yet
+	PersistedQueries bool
+
+	client   Client
+	endpoint string
+	opts     SendOptions
+}
+
+type graphQLPayload struct {
+	Query         string                 `json:"query,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     interface{}            `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// NewGraphQLClient returns a GraphQL client posting to endpoint with the query params and headers of opts
+func NewGraphQLClient(c Client, endpoint string, opts SendOptions) *GraphQLClient {
+	return &GraphQLClient{
+		client:   c,
+		endpoint: endpoint,
+		opts:     opts.SetContentType("application/json"),
+	}
+}
+
+// Do executes a query or a mutation and decodes the data member into data, which may be nil.
+// The errors array of the response is returned as GraphQLErrors
+func (g *GraphQLClient) Do(ctx context.Context, req GraphQLRequest, data interface{}) error {
+	payload := graphQLPayload{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+	}
+
+	if g.PersistedQueries {
+		hash := sha256.Sum256([]byte(req.Query))
+		payload.Extensions = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": hex.EncodeToString(hash[:]),
+			},
+		}
+		payload.Query = ""
+
+		res, err := g.send(ctx, payload)
+		if err != nil {
+			return err
+		}
+		if !res.Errors.persistedQueryNotFound() {
+			return res.decode(data)
+		}
+		payload.Query = req.Query
+	}
+
+	res, err := g.send(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	return res.decode(data)
+}
+
+func (g *GraphQLClient) send(ctx context.Context, payload graphQLPayload) (*graphQLResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := SendWithContext(ctx, g.client, http.MethodPost, g.endpoint, g.opts, body)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &graphQLResponse{}
+	if err := json.Unmarshal(resp.Body, res); err != nil {
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("graphql: unexpected status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("graphql: unable to decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 && len(res.Errors) == 0 {
+		return nil, fmt.Errorf("graphql: unexpected status %d", resp.StatusCode)
+	}
+
+	return res, nil
+}
+
+func (r *graphQLResponse) decode(data interface{}) error {
+	if data != nil && len(r.Data) > 0 && string(r.Data) != "null" {
+		if err := json.Unmarshal(r.Data, data); err != nil {
+			return fmt.Errorf("graphql: unable to decode data: %w", err)
+		}
+	}
+	if len(r.Errors) > 0 {
+		return r.Errors
+	}
+
+	return nil
+}
+
+// persistedQueryNotFound reports whether the server asks for the query document
+func (e GraphQLErrors) persistedQueryNotFound() bool {
+	for _, err := range e {
+		if err.Message == persistedQueryNotFound || err.Extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+
+	return false
+}
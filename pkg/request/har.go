@@ -0,0 +1,475 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultHARMaxBodySize = 64 * 1024
+	harRedacted           = "[REDACTED]"
+)
+
+// defaultHARRedactHeaders are the headers redacted when HAROptions.RedactHeaders is empty
+var defaultHARRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// HAR is an HTTP Archive 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of the archive
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator names the application which recorded the archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one attempt of a request, retries are recorded as separate entries
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	// Attempt is 0 for the first attempt of a call, then the retry number
+	Attempt int    `json:"_attempt"`
+	Error   string `json:"_error,omitempty"`
+}
+
+// HARRequest is the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response of an entry, its status is 0 when the attempt failed
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string         `json:"mimeType"`
+	Params   []HARNameValue `json:"params"`
+	Text     string         `json:"text"`
+	Comment  string         `json:"comment,omitempty"`
+}
+
+// HARContent is the body of a response
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARNameValue is a header, cookie or query param
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARTimings are the phases of an entry in milliseconds, -1 when not measured
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HAROptions configures a HARRecorder
+type HAROptions struct {
+	// MaxBodySize caps the recorded request and response bodies, 64KB by default
+	MaxBodySize int
+	// RedactHeaders are the headers whose values are replaced,
+	// Authorization, Proxy-Authorization, Cookie and Set-Cookie by default
+	RedactHeaders []string
+	// RedactQuery are the query params whose values are replaced
+	RedactQuery []string
+	// Redact is called on each entry before it is recorded, e.g. to mask body fields
+	Redact func(*HAREntry)
+}
+
+// HARRecorder records the traffic of a client as an HTTP Archive, viewable in browser devtools
+type HARRecorder struct {
+	opts          HAROptions
+	redactHeaders map[string]bool
+	redactQuery   map[string]bool
+
+	mu      sync.Mutex
+	entries []*HAREntry
+}
+
+// NewHARRecorder returns an empty recorder
+func NewHARRecorder(opts HAROptions) *HARRecorder {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultHARMaxBodySize
+	}
+	if len(opts.RedactHeaders) == 0 {
+		opts.RedactHeaders = defaultHARRedactHeaders
+	}
+
+	r := &HARRecorder{
+		opts:          opts,
+		redactHeaders: make(map[string]bool),
+		redactQuery:   make(map[string]bool),
+	}
+	for _, name := range opts.RedactHeaders {
+		r.redactHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range opts.RedactQuery {
+		r.redactQuery[name] = true
+	}
+
+	return r
+}
+
+// WithHARRecorder records every attempt of the client requests into r
+func WithHARRecorder(r *HARRecorder) OptionClient {
+	return WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &harTransport{recorder: r, next: next}
+	})
+}
+
+// HAR returns the archive of the entries recorded so far
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*HAREntry, len(r.entries))
+	copy(entries, r.entries)
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "golang-common/request", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// WriteTo writes the archive as JSON
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.MarshalIndent(r.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+
+	return int64(n), err
+}
+
+// WriteFile writes the archive to path, usually with a .har extension
+func (r *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Reset drops the recorded entries
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+func (r *HARRecorder) add(entry *HAREntry) {
+	if r.opts.Redact != nil {
+		r.opts.Redact(entry)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+func (r *HARRecorder) headers(h http.Header) []HARNameValue {
+	values := make([]HARNameValue, 0, len(h))
+	for name, vals := range h {
+		for _, val := range vals {
+			if r.redactHeaders[name] {
+				val = harRedacted
+			}
+			values = append(values, HARNameValue{Name: name, Value: val})
+		}
+	}
+
+	return values
+}
+
+func (r *HARRecorder) redactURL(u *url.URL) (string, []HARNameValue) {
+	query := u.Query()
+	params := make([]HARNameValue, 0, len(query))
+	for name, vals := range query {
+		for i, val := range vals {
+			if r.redactQuery[name] {
+				vals[i] = harRedacted
+				val = harRedacted
+			}
+			params = append(params, HARNameValue{Name: name, Value: val})
+		}
+	}
+	redacted := *u
+	if len(r.redactQuery) > 0 {
+		redacted.RawQuery = query.Encode()
+	}
+
+	return redacted.String(), params
+}
+
+// harTransport records each round trip into the recorder
+type harTransport struct {
+	recorder *HARRecorder
+	next     http.RoundTripper
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := t.recorder
+	start := time.Now()
+	entry := &HAREntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Timings:         HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if state := getCallState(req.Context()); state != nil {
+		entry.Attempt = state.attempt
+	}
+	targetURL, query := r.redactURL(req.URL)
+	entry.Request = HARRequest{
+		Method:      req.Method,
+		URL:         targetURL,
+		HTTPVersion: req.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     r.headers(req.Header),
+		QueryString: query,
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+
+	// the entry is added once the response and the request body are both done,
+	// the transport may still be sending the body after RoundTrip returns
+	pending := &harPending{recorder: r, entry: entry, left: 1}
+	if req.Body != nil && req.Body != http.NoBody {
+		pending.left++
+		reqBody := &cappedBuffer{max: r.opts.MaxBodySize}
+		mimeType := req.Header.Get("Content-Type")
+		req = req.Clone(req.Context())
+		req.Body = &teeReadCloser{r: req.Body, w: reqBody, done: func() {
+			text, size, comment := reqBody.snapshot()
+			entry.Request.BodySize = size
+			entry.Request.PostData = &HARPostData{
+				MimeType: mimeType,
+				Params:   []HARNameValue{},
+				Text:     text,
+				Comment:  comment,
+			}
+			pending.done()
+		}}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	headersAt := time.Now()
+	entry.Timings.Wait = millis(headersAt.Sub(start))
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		entry.Time = entry.Timings.Wait
+		pending.done()
+		return resp, err
+	}
+
+	entry.Response = HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     r.headers(resp.Header),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	respBody := &cappedBuffer{max: r.opts.MaxBodySize}
+	resp.Body = &harBody{
+		ReadCloser: resp.Body,
+		buf:        respBody,
+		done: func(readErr error) {
+			end := time.Now()
+			entry.Timings.Receive = millis(end.Sub(headersAt))
+			entry.Time = millis(end.Sub(start))
+			entry.Response.BodySize = respBody.size
+			entry.Response.Content = harContent(respBody, resp.Header.Get("Content-Type"))
+			if readErr != nil {
+				entry.Error = readErr.Error()
+			}
+			pending.done()
+		},
+	}
+
+	return resp, nil
+}
+
+func harContent(body *cappedBuffer, mimeType string) HARContent {
+	content := HARContent{Size: body.size, MimeType: mimeType, Comment: body.comment()}
+	if utf8.Valid(body.buf) {
+		content.Text = string(body.buf)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body.buf)
+		content.Encoding = "base64"
+	}
+
+	return content
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// harPending adds the entry to the recorder when its last part is done
+type harPending struct {
+	recorder *HARRecorder
+	entry    *HAREntry
+
+	mu   sync.Mutex
+	left int
+}
+
+func (p *harPending) done() {
+	p.mu.Lock()
+	p.left--
+	last := p.left == 0
+	p.mu.Unlock()
+	if last {
+		p.recorder.add(p.entry)
+	}
+}
+
+// cappedBuffer keeps the first max bytes written and counts all of them
+type cappedBuffer struct {
+	max  int
+	mu   sync.Mutex
+	buf  []byte
+	size int64
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size += int64(len(p))
+	if room := b.max - len(b.buf); room > 0 {
+		if len(p) > room {
+			b.buf = append(b.buf, p[:room]...)
+		} else {
+			b.buf = append(b.buf, p...)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (b *cappedBuffer) comment() string {
+	if b.size > int64(len(b.buf)) {
+		return "body truncated to " + strconv.Itoa(len(b.buf)) + " bytes"
+	}
+	return ""
+}
+
+// snapshot returns the kept bytes as text, the total size and the truncation comment
+func (b *cappedBuffer) snapshot() (string, int64, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf), b.size, b.comment()
+}
+
+// teeReadCloser copies what is read from r into w, done is called once at EOF, error or Close
+type teeReadCloser struct {
+	r    io.ReadCloser
+	w    io.Writer
+	once sync.Once
+	done func()
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.w.Write(p[:n])
+	}
+	if err != nil {
+		t.once.Do(t.done)
+	}
+	return n, err
+}
+
+func (t *teeReadCloser) Close() error {
+	err := t.r.Close()
+	t.once.Do(t.done)
+	return err
+}
+
+// harBody records the response body as it is read, the entry is completed at EOF or Close
+type harBody struct {
+	io.ReadCloser
+	buf  *cappedBuffer
+	once sync.Once
+	done func(error)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.buf.Write(p[:n])
+	}
+	if err == io.EOF {
+		b.once.Do(func() { b.done(nil) })
+	} else if err != nil {
+		b.once.Do(func() { b.done(err) })
+	}
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.once.Do(func() { b.done(nil) })
+	return b.ReadCloser.Close()
+}
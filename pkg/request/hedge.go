@@ -0,0 +1,150 @@
+package request
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHedgeMaxAttempts = 2
+	defaultHedgeDelay       = 100 * time.Millisecond
+	hedgeLatencyWindow      = 100
+	hedgeMinSamples         = 20
+)
+
+// HedgeOptions configures hedged GET requests
+type HedgeOptions struct {
+	// Delay before launching the next attempt while the previous ones are still in flight, 100ms by default
+	Delay time.Duration
+	// Percentile, between 0 and 100, of the recent GET latencies used as delay
+	// instead of Delay once enough calls were observed
+	Percentile float64
+	// MaxAttempts is the total number of attempts including the first one, 2 by default
+	MaxAttempts int
+}
+
+type hedger struct {
+	HedgeOptions
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// WithHedging enables hedged requests on Get. When the response is slower than the delay,
+// another attempt is launched, the first successful response is returned and the others are canceled
+func WithHedging(opts HedgeOptions) OptionClient {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultHedgeMaxAttempts
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = defaultHedgeDelay
+	}
+	return func(r *ClientOptions) {
+		r.hedge = &hedger{HedgeOptions: opts}
+	}
+}
+
+// delay returns the time to wait before launching the next attempt
+func (h *hedger) delay() time.Duration {
+	if h.Percentile <= 0 {
+		return h.Delay
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeMinSamples {
+		return h.Delay
+	}
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(math.Ceil(h.Percentile/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+
+	return sorted[idx]
+}
+
+// observe records the latency of a final response
+func (h *hedger) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeLatencyWindow {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeLatencyWindow
+}
+
+// hedge sends the request and launches another attempt each time the delay elapses
+// without a final response, up to MaxAttempts. Any response below 500 is final,
+// along with the error it may carry such as a *ProblemError
+func (c client) hedge(req *http.Request) (*Response, error) {
+	type result struct {
+		resp    *Response
+		err     error
+		attempt int
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	h := c.options.hedge
+	labels := map[string]string{"method": req.Method, "host": req.URL.Host}
+	results := make(chan result, h.MaxAttempts)
+	launch := func(attempt int) {
+		// each attempt gets its own call state as they run concurrently
+		attemptCtx := ctx
+		if state := getCallState(ctx); state != nil {
+			attemptState := *state
+			attemptCtx = context.WithValue(ctx, callStateKey{}, &attemptState)
+		}
+		go func() {
+			resp, err := c.do(req.Clone(attemptCtx))
+			results <- result{resp: resp, err: err, attempt: attempt}
+		}()
+	}
+
+	start := time.Now()
+	delay := h.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	launch(0)
+	launched, inflight := 1, 1
+	var last result
+	for inflight > 0 {
+		select {
+		case <-timer.C:
+			if launched < h.MaxAttempts {
+				launch(launched)
+				launched++
+				inflight++
+				c.options.incCounter(MetricHedgedRequests, labels)
+				timer.Reset(delay)
+			}
+		case res := <-results:
+			inflight--
+			if res.resp != nil && res.resp.StatusCode < http.StatusInternalServerError {
+				h.observe(time.Since(start))
+				if res.attempt > 0 {
+					c.options.incCounter(MetricHedgeWins, labels)
+				}
+				return res.resp, res.err
+			}
+			last = res
+		}
+	}
+
+	return last.resp, last.err
+}
@@ -0,0 +1,88 @@
+package request
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// RetryEvent describes a retry attempt of a call
+type RetryEvent struct {
+	// Attempt is 1 for the first retry
+	Attempt int
+	// Err is the error of the previous attempt, nil when it was retried on its status
+	Err error
+	// StatusCode is the status of the previous attempt, zero when it failed
+	StatusCode int
+	// Wait is the time waited before this attempt
+	Wait time.Duration
+}
+
+// Hooks are callbacks run along the lifecycle of the calls, nil callbacks are skipped
+type Hooks struct {
+	// OnRequest is called once before a call is sent
+	OnRequest func(req *http.Request)
+	// OnRetry is called before each retry attempt
+	OnRetry func(req *http.Request, event RetryEvent)
+	// OnResponse is called with the final response of a call
+	OnResponse func(req *http.Request, resp *Response)
+	// OnError is called with the final error of a call
+	OnError func(req *http.Request, err error)
+}
+
+// WithHooks adds lifecycle hooks to the client, several hooks run in the order they were added
+func WithHooks(h Hooks) OptionClient {
+	return func(r *ClientOptions) {
+		r.hooks = append(r.hooks, h)
+	}
+}
+
+func (o *ClientOptions) onRequest(req *http.Request) {
+	for _, h := range o.hooks {
+		if h.OnRequest != nil {
+			h.OnRequest(req)
+		}
+	}
+}
+
+func (o *ClientOptions) onResult(req *http.Request, resp *Response, err error) {
+	for _, h := range o.hooks {
+		if resp != nil && h.OnResponse != nil {
+			h.OnResponse(req, resp)
+		}
+		if err != nil && h.OnError != nil {
+			h.OnError(req, err)
+		}
+	}
+}
+
+// retryHook wraps the request log hook of the retryable client to track
+// the attempt of the call and run the OnRetry hooks
+func (o *ClientOptions) retryHook(next retryablehttp.RequestLogHook) retryablehttp.RequestLogHook {
+	return func(logger retryablehttp.Logger, req *http.Request, attempt int) {
+		if next != nil {
+			next(logger, req, attempt)
+		}
+		state := getCallState(req.Context())
+		if state == nil {
+			return
+		}
+		state.attempt = attempt
+		if attempt == 0 {
+			return
+		}
+
+		event := RetryEvent{
+			Attempt:    attempt,
+			Err:        state.retryErr,
+			StatusCode: state.retryStatus,
+			Wait:       time.Since(state.retryAt),
+		}
+		for _, h := range o.hooks {
+			if h.OnRetry != nil {
+				h.OnRetry(req, event)
+			}
+		}
+	}
+}
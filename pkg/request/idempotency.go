@@ -0,0 +1,62 @@
+package request
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKey generates an idempotency key for each call of the given methods,
+// POST and PATCH when no method is given. The same key is sent on every retry attempt
+// and the call becomes retryable whatever the retry methods are
+func WithIdempotencyKey(methods ...string) OptionClient {
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	return func(r *ClientOptions) {
+		r.idempotencyMethods = methodSet(methods)
+	}
+}
+
+// SetIdempotencyKey sets the idempotency key of the request
+func (opt SendOptions) SetIdempotencyKey(key string) SendOptions {
+	newOpt := opt
+	if newOpt == nil {
+		newOpt = make(SendOptions)
+	}
+	if newOpt[HeaderParam] == nil {
+		newOpt[HeaderParam] = make(map[string]interface{})
+	}
+	newOpt[HeaderParam][IdempotencyKeyHeader] = key
+
+	return newOpt
+}
+
+// NewIdempotencyKey returns a random UUID (version 4) to be used as idempotency key
+func NewIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// setIdempotencyKey generates the key of a logical call unless the caller supplied one
+func (o *ClientOptions) setIdempotencyKey(req *http.Request) error {
+	if !o.idempotencyMethods[req.Method] || req.Header.Get(IdempotencyKeyHeader) != "" {
+		return nil
+	}
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(IdempotencyKeyHeader, key)
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+const jsonRPCVersion = "2.0"
+
+// JSONRPCError is the error object of a JSON-RPC 2.0 response
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc: error %d: %s", e.Code, e.Message)
+}
+
+// JSONRPCCall is one call of a batch. Result is decoded from the response and
+// Err holds its error, notifications get neither
+type JSONRPCCall struct {
+	Method       string
+	Params       interface{}
+	Result       interface{}
+	Notification bool
+	Err          error
+}
+
+// JSONRPCClient is a JSON-RPC 2.0 client over HTTP sending its calls through a Client
+type JSONRPCClient struct {
+	client   Client
+	endpoint string
+	opts     SendOptions
+	nextID   uint64
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      *uint64     `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *JSONRPCError   `json:"error"`
+	ID      *uint64         `json:"id"`
+}
+
+// NewJSONRPCClient returns a JSON-RPC client posting to endpoint with the query params and headers of opts
+func NewJSONRPCClient(c Client, endpoint string, opts SendOptions) *JSONRPCClient {
+	return &JSONRPCClient{
+		client:   c,
+		endpoint: endpoint,
+		opts:     opts.SetContentType("application/json"),
+	}
+}
+
+// Call invokes method and decodes its result into result, which may be nil.
+// An error object of the response is returned as *JSONRPCError
+func (r *JSONRPCClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	call := &JSONRPCCall{Method: method, Params: params, Result: result}
+	if err := r.Batch(ctx, []*JSONRPCCall{call}); err != nil {
+		return err
+	}
+
+	return call.Err
+}
+
+// Notify sends a notification, no response is expected
+func (r *JSONRPCClient) Notify(ctx context.Context, method string, params interface{}) error {
+	return r.Batch(ctx, []*JSONRPCCall{{Method: method, Params: params, Notification: true}})
+}
+
+// Batch sends the calls in one request, a single call is not wrapped in an array.
+// The responses are matched to the calls by id. The returned error reports a transport
+// or protocol failure, the error of each call is set in its Err
+func (r *JSONRPCClient) Batch(ctx context.Context, calls []*JSONRPCCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint64]*JSONRPCCall, len(calls))
+	reqs := make([]jsonRPCRequest, len(calls))
+	for i, call := range calls {
+		reqs[i] = jsonRPCRequest{JSONRPC: jsonRPCVersion, Method: call.Method, Params: call.Params}
+		if !call.Notification {
+			id := atomic.AddUint64(&r.nextID, 1)
+			reqs[i].ID = &id
+			byID[id] = call
+		}
+	}
+
+	var payload interface{} = reqs
+	if len(reqs) == 1 {
+		payload = reqs[0]
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := SendWithContext(ctx, r.client, http.MethodPost, r.endpoint, r.opts, body)
+	if err != nil {
+		return err
+	}
+	if len(byID) == 0 {
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("jsonrpc: unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	responses, err := parseJSONRPCResponses(resp.Body)
+	if err != nil {
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("jsonrpc: unexpected status %d", resp.StatusCode)
+		}
+		return err
+	}
+
+	for _, res := range responses {
+		if res.ID == nil {
+			// the server could not read the ids of the request
+			if res.Error != nil {
+				return res.Error
+			}
+			continue
+		}
+		call, ok := byID[*res.ID]
+		if !ok {
+			continue
+		}
+		delete(byID, *res.ID)
+
+		switch {
+		case res.Error != nil:
+			call.Err = res.Error
+		case call.Result != nil && len(res.Result) > 0:
+			if err := json.Unmarshal(res.Result, call.Result); err != nil {
+				call.Err = fmt.Errorf("jsonrpc: unable to decode result of %s: %w", call.Method, err)
+			}
+		}
+	}
+	for id, call := range byID {
+		call.Err = fmt.Errorf("jsonrpc: no response for %s (id %d)", call.Method, id)
+	}
+
+	return nil
+}
+
+// parseJSONRPCResponses decodes a single response or a batch of responses
+func parseJSONRPCResponses(body []byte) ([]jsonRPCResponse, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, errors.New("jsonrpc: empty response")
+	}
+
+	var responses []jsonRPCResponse
+	if body[0] == '[' {
+		if err := json.Unmarshal(body, &responses); err != nil {
+			return nil, fmt.Errorf("jsonrpc: unable to decode response: %w", err)
+		}
+		return responses, nil
+	}
+
+	var res jsonRPCResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("jsonrpc: unable to decode response: %w", err)
+	}
+
+	return append(responses, res), nil
+}
@@ -0,0 +1,27 @@
+package request
+
+// Metric names reported by the client
+const (
+	MetricHedgedRequests    = "http_client_hedged_requests_total"
+	MetricHedgeWins         = "http_client_hedge_wins_total"
+	MetricRetriesSuppressed = "http_client_retries_suppressed_total"
+)
+
+// Metrics receives the metrics reported by the client,
+// it can be backed by prometheus, statsd or anything else
+type Metrics interface {
+	IncCounter(name string, labels map[string]string)
+}
+
+// WithMetrics sets the metrics collector of the client
+func WithMetrics(m Metrics) OptionClient {
+	return func(r *ClientOptions) {
+		r.metrics = m
+	}
+}
+
+func (o *ClientOptions) incCounter(name string, labels map[string]string) {
+	if o.metrics != nil {
+		o.metrics.IncCounter(name, labels)
+	}
+}
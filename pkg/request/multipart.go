@@ -0,0 +1,208 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Multipart builds a multipart/form-data body. The parts are streamed when the request
+// is sent and the body is rebuilt on every retry attempt, files are reopened each time
+//
+//	form := request.NewMultipart().
+//		AddField("name", "report").
+//		AddFilePath("file", "/tmp/report.csv", "text/csv")
+//	resp, err := request.SendMultipart(ctx, client, http.MethodPost, url, nil, form)
+type Multipart struct {
+	boundary string
+	parts    []multipartPart
+}
+
+type multipartPart struct {
+	fieldName   string
+	fileName    string
+	contentType string
+	value       string
+	open        func() (io.ReadCloser, error)
+}
+
+// NewMultipart returns an empty multipart body with a random boundary
+func NewMultipart() *Multipart {
+	return &Multipart{
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+// AddField adds a text field
+func (m *Multipart) AddField(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{fieldName: name, value: value})
+	return m
+}
+
+// AddFile adds a file part whose content is read from open on every attempt,
+// contentType defaults to application/octet-stream
+func (m *Multipart) AddFile(fieldName, fileName, contentType string, open func() (io.ReadCloser, error)) *Multipart {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	m.parts = append(m.parts, multipartPart{
+		fieldName:   fieldName,
+		fileName:    fileName,
+		contentType: contentType,
+		open:        open,
+	})
+	return m
+}
+
+// AddFilePath adds a file part read from the local path
+func (m *Multipart) AddFilePath(fieldName, path, contentType string) *Multipart {
+	return m.AddFile(fieldName, filepath.Base(path), contentType, func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+}
+
+// ContentType returns the multipart/form-data content type carrying the boundary
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// Reader returns a new reader streaming the whole body
+func (m *Multipart) Reader() (io.Reader, error) {
+	return &multipartReader{form: m}, nil
+}
+
+// writeTo writes every part to w
+func (m *Multipart) writeTo(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, part := range m.parts {
+		if part.open == nil {
+			if err := mw.WriteField(part.fieldName, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(part.fieldName), quoteEscaper.Replace(part.fileName)))
+		header.Set("Content-Type", part.contentType)
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if err := copyPart(pw, part.open); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+func copyPart(w io.Writer, open func() (io.ReadCloser, error)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// multipartReader starts writing the parts through a pipe on the first Read
+type multipartReader struct {
+	form *Multipart
+	once sync.Once
+	pr   *io.PipeReader
+}
+
+func (r *multipartReader) start() {
+	pr, pw := io.Pipe()
+	r.pr = pr
+	go func() {
+		pw.CloseWithError(r.form.writeTo(pw))
+	}()
+}
+
+func (r *multipartReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	return r.pr.Read(p)
+}
+
+func (r *multipartReader) Close() error {
+	r.once.Do(func() {})
+	if r.pr == nil {
+		return nil
+	}
+	return r.pr.Close()
+}
+
+// SendMultipart sends the multipart body bound to ctx and returns response from target URL
+func SendMultipart(ctx context.Context, c Client, method, targetURL string, opts SendOptions, form *Multipart) (*Response, error) {
+	return streamingClient(c).sendMultipart(ctx, method, targetURL, opts, form)
+}
+
+func (c client) sendMultipart(ctx context.Context, method, targetURL string, opts SendOptions, form *Multipart) (*Response, error) {
+	if c.debugEnable {
+		c.logger.WithFields(logrus.Fields{
+			"method": method,
+			"url":    targetURL,
+			"opts":   opts,
+			"parts":  len(form.parts),
+		}).Debug("[SendMultipart]: http request")
+	}
+
+	req, err := newRequest(ctx, method, targetURL, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.ContentType())
+
+	return c.doStream(req, opts, form.Reader)
+}
+
+// doStream sends the request with a body rebuilt by body on every attempt.
+// The retryable client is used directly so the body is never buffered
+func (c client) doStream(req *http.Request, opts SendOptions, body retryablehttp.ReaderFunc) (*Response, error) {
+	if err := c.options.setIdempotencyKey(req); err != nil {
+		return nil, err
+	}
+	req = req.WithContext(c.options.withCallState(req.Context(), req, opts))
+
+	retryableReq, err := retryablehttp.FromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := retryableReq.SetBody(body); err != nil {
+		return nil, err
+	}
+
+	c.options.onRequest(req)
+	netResponse, err := c.retryClient(req).Do(retryableReq)
+	if err != nil {
+		response, err := exhaustedProblem(req, err)
+		c.options.onResult(req, response, err)
+		return response, err
+	}
+	response, err := c.readResponse(netResponse)
+	err = validateResponse(opts, response, err)
+	c.options.onResult(req, response, err)
+
+	return response, err
+}
@@ -0,0 +1,97 @@
+package request
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// SettingParam is the SendOptions key of the per call settings
+const SettingParam = "settings"
+
+const (
+	settingTimeout     = "timeout"
+	settingRetryMax    = "retry_max"
+	settingRetryPolicy = "retry_policy"
+)
+
+func (opt SendOptions) setSetting(key string, val interface{}) SendOptions {
+	newOpt := opt
+	if newOpt == nil {
+		newOpt = make(SendOptions)
+	}
+	if newOpt[SettingParam] == nil {
+		newOpt[SettingParam] = make(map[string]interface{})
+	}
+	newOpt[SettingParam][key] = val
+
+	return newOpt
+}
+
+// SetTimeout overrides the client timeout of each attempt for this call
+func (opt SendOptions) SetTimeout(timeout time.Duration) SendOptions {
+	return opt.setSetting(settingTimeout, timeout)
+}
+
+// SetRetryMax overrides the client max retry for this call
+func (opt SendOptions) SetRetryMax(retryMax int) SendOptions {
+	return opt.setSetting(settingRetryMax, retryMax)
+}
+
+// SetRetryPolicy overrides the client retry policy for this call,
+// the retry methods of the client still apply
+func (opt SendOptions) SetRetryPolicy(policy retryablehttp.CheckRetry) SendOptions {
+	return opt.setSetting(settingRetryPolicy, policy)
+}
+
+// callClient returns a retryable client carrying the settings of opts,
+// nil when the call has none. The shared client is left untouched
+func (o *ClientOptions) callClient(opts SendOptions) *retryablehttp.Client {
+	settings := opts[SettingParam]
+	if len(settings) == 0 {
+		return nil
+	}
+
+	rc := &retryablehttp.Client{
+		HTTPClient:      o.HTTPClient,
+		Logger:          o.Logger,
+		RetryWaitMin:    o.RetryWaitMin,
+		RetryWaitMax:    o.RetryWaitMax,
+		RetryMax:        o.RetryMax,
+		RequestLogHook:  o.RequestLogHook,
+		ResponseLogHook: o.ResponseLogHook,
+		CheckRetry:      o.CheckRetry,
+		Backoff:         o.Backoff,
+		ErrorHandler:    o.ErrorHandler,
+	}
+	if timeout, ok := settings[settingTimeout].(time.Duration); ok {
+		httpClient := *o.HTTPClient
+		httpClient.Timeout = timeout
+		rc.HTTPClient = &httpClient
+	}
+	if retryMax, ok := settings[settingRetryMax].(int); ok {
+		rc.RetryMax = retryMax
+	}
+	if policy, ok := settings[settingRetryPolicy].(retryablehttp.CheckRetry); ok && policy != nil {
+		rc.CheckRetry = o.checkRetry(policy)
+	}
+
+	return rc
+}
+
+// httpClient returns the client sending the request, the one of its call settings if any
+func (c client) httpClient(req *http.Request) *http.Client {
+	if state := getCallState(req.Context()); state != nil && state.retryClient != nil {
+		return state.retryClient.StandardClient()
+	}
+	return c.HTTPClient
+}
+
+// retryClient returns the retryable client sending the request, the one of its call settings if any
+func (c client) retryClient(req *http.Request) *retryablehttp.Client {
+	if state := getCallState(req.Context()); state != nil && state.retryClient != nil {
+		return state.retryClient
+	}
+	return c.options.Client
+}
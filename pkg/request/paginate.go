@@ -0,0 +1,324 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultMaxPages = 100
+
+// ErrMaxPagesReached is returned by Paginator.Err when the pagination stopped at MaxPages
+var ErrMaxPagesReached = errors.New("paginate: max pages reached")
+
+// PageStyle selects how the next page of a list endpoint is requested
+type PageStyle int
+
+const (
+	// PageStyleLink follows the RFC 5988 `Link: <...>; rel="next"` header
+	PageStyleLink PageStyle = iota
+	// PageStyleCursor sends the cursor found in the JSON body at CursorPath as CursorParam
+	PageStyleCursor
+	// PageStyleOffset increments OffsetParam by Limit until a page is not full
+	PageStyleOffset
+	// PageStylePageNumber increments PageParam until a page is empty
+	PageStylePageNumber
+)
+
+// PaginateOptions configures a Paginator
+type PaginateOptions struct {
+	Style PageStyle
+	// ItemsPath is the dot separated JSON path of the items array, the body itself when empty
+	ItemsPath string
+	// CursorPath is the dot separated JSON path of the next cursor, e.g. "meta.next_cursor"
+	CursorPath string
+	// CursorParam is the query param carrying the cursor, "cursor" by default
+	CursorParam string
+	// OffsetParam and LimitParam default to "offset" and "limit"
+	OffsetParam string
+	LimitParam  string
+	// Limit is the page size sent as LimitParam
+	Limit int
+	// PageParam defaults to "page", FirstPage to 1 unless ZeroBasedPages is set
+	PageParam      string
+	FirstPage      int
+	ZeroBasedPages bool
+	// MaxPages stops the pagination with ErrMaxPagesReached, 100 by default
+	MaxPages int
+}
+
+// Paginator fetches the pages of a list endpoint lazily and yields their items
+//
+//	p := request.Paginate[User](ctx, client, "https://api/users", nil, request.PaginateOptions{})
+//	for p.Next() {
+//		user := p.Item()
+//	}
+//	if err := p.Err(); err != nil {
+//	}
+type Paginator[T any] struct {
+	ctx    context.Context
+	client Client
+	opts   SendOptions
+	popts  PaginateOptions
+
+	nextURL string
+	cursor  string
+	offset  int
+	page    int
+	pages   int
+	done    bool
+
+	items []T
+	pos   int
+	item  T
+	err   error
+}
+
+// Paginate returns a Paginator over targetURL, no request is sent before the first Next
+func Paginate[T any](ctx context.Context, c Client, targetURL string, opts SendOptions, popts PaginateOptions) *Paginator[T] {
+	if popts.CursorParam == "" {
+		popts.CursorParam = "cursor"
+	}
+	if popts.OffsetParam == "" {
+		popts.OffsetParam = "offset"
+	}
+	if popts.LimitParam == "" {
+		popts.LimitParam = "limit"
+	}
+	if popts.PageParam == "" {
+		popts.PageParam = "page"
+	}
+	if popts.FirstPage == 0 && !popts.ZeroBasedPages {
+		popts.FirstPage = 1
+	}
+	if popts.MaxPages <= 0 {
+		popts.MaxPages = defaultMaxPages
+	}
+
+	return &Paginator[T]{
+		ctx:     ctx,
+		client:  c,
+		opts:    opts,
+		popts:   popts,
+		nextURL: targetURL,
+		page:    popts.FirstPage,
+	}
+}
+
+// Next advances to the next item, fetching the next page when needed.
+// It returns false at the end of the list or on error
+func (p *Paginator[T]) Next() bool {
+	for p.pos >= len(p.items) {
+		if p.done || p.err != nil {
+			return false
+		}
+		if p.pages >= p.popts.MaxPages {
+			p.err = ErrMaxPagesReached
+			return false
+		}
+		if err := p.fetch(); err != nil {
+			p.err = err
+			return false
+		}
+	}
+	p.item = p.items[p.pos]
+	p.pos++
+
+	return true
+}
+
+// Item returns the current item
+func (p *Paginator[T]) Item() T {
+	return p.item
+}
+
+// Err returns the error which stopped the pagination
+func (p *Paginator[T]) Err() error {
+	return p.err
+}
+
+// fetch requests the next page and prepares the following one
+func (p *Paginator[T]) fetch() error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+
+	targetURL, opts := p.nextURL, p.pageOptions()
+	resp, err := SendWithContext(p.ctx, p.client, http.MethodGet, targetURL, opts, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("paginate: unexpected status %d from %s", resp.StatusCode, targetURL)
+	}
+	p.pages++
+
+	rawItems, err := jsonPath(resp.Body, p.popts.ItemsPath)
+	if err != nil {
+		return err
+	}
+	var items []T
+	if len(rawItems) > 0 && !bytes.Equal(rawItems, []byte("null")) {
+		if err := json.Unmarshal(rawItems, &items); err != nil {
+			return fmt.Errorf("paginate: unable to decode items: %w", err)
+		}
+	}
+	p.items, p.pos = items, 0
+
+	switch p.popts.Style {
+	case PageStyleLink:
+		next := parseLinkHeader(resp.Header)["next"]
+		if next == "" {
+			p.done = true
+			return nil
+		}
+		nextURL, err := resolveURL(targetURL, next)
+		if err != nil {
+			return err
+		}
+		p.nextURL = nextURL
+	case PageStyleCursor:
+		rawCursor, err := jsonPath(resp.Body, p.popts.CursorPath)
+		if err != nil {
+			return err
+		}
+		p.cursor = jsonScalar(rawCursor)
+		p.done = p.cursor == ""
+	case PageStyleOffset:
+		p.offset += len(items)
+		p.done = len(items) == 0 || (p.popts.Limit > 0 && len(items) < p.popts.Limit)
+	case PageStylePageNumber:
+		p.page++
+		p.done = len(items) == 0
+	}
+
+	return nil
+}
+
+// pageOptions returns the send options of the next page
+func (p *Paginator[T]) pageOptions() SendOptions {
+	params := make(map[string]interface{})
+	for key, val := range p.opts[QueryParam] {
+		params[key] = val
+	}
+
+	switch p.popts.Style {
+	case PageStyleLink:
+		// the next link already carries the query params
+		if p.pages > 0 {
+			params = nil
+		}
+	case PageStyleCursor:
+		if p.cursor != "" {
+			params[p.popts.CursorParam] = p.cursor
+		}
+	case PageStyleOffset:
+		params[p.popts.OffsetParam] = strconv.Itoa(p.offset)
+		if p.popts.Limit > 0 {
+			params[p.popts.LimitParam] = strconv.Itoa(p.popts.Limit)
+		}
+	case PageStylePageNumber:
+		params[p.popts.PageParam] = strconv.Itoa(p.page)
+	}
+
+	opts := make(SendOptions, len(p.opts))
+	for key, val := range p.opts {
+		opts[key] = val
+	}
+	opts[QueryParam] = params
+
+	return opts
+}
+
+// parseLinkHeader returns the targets of the RFC 5988 Link headers by relation type
+func parseLinkHeader(header http.Header) map[string]string {
+	links := make(map[string]string)
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			for _, param := range parts[1:] {
+				key, val, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					links[strings.ToLower(rel)] = target
+				}
+			}
+		}
+	}
+
+	return links
+}
+
+// resolveURL resolves a possibly relative reference against base
+func resolveURL(base, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+// jsonPath returns the raw JSON value at the dot separated path, array elements are selected by index
+func jsonPath(body []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(body)
+	if path == "" {
+		return raw, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		if idx, err := strconv.Atoi(key); err == nil {
+			var arr []json.RawMessage
+			if err := json.Unmarshal(raw, &arr); err != nil {
+				return nil, fmt.Errorf("json path %q: %w", path, err)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, nil
+			}
+			raw = arr[idx]
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("json path %q: %w", path, err)
+		}
+		val, ok := obj[key]
+		if !ok {
+			return nil, nil
+		}
+		raw = val
+	}
+
+	return raw, nil
+}
+
+// jsonScalar returns a JSON string or number as string, empty for null or missing values
+func jsonScalar(raw json.RawMessage) string {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,158 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxProblemSize bounds the problem document kept from a retried response
+const maxProblemSize = 1 << 20
+
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an RFC 7807 problem document, the members not defined
+// by the RFC are kept in Extensions
+type ProblemDetails struct {
+	Type       string                 `json:"type,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// UnmarshalJSON decodes the standard members and collects the others into Extensions
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type problem ProblemDetails
+	var std problem
+	if err := json.Unmarshal(data, &std); err != nil {
+		return err
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+
+	*p = ProblemDetails(std)
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+
+	return nil
+}
+
+// ProblemError is returned with an error response carrying a problem document.
+// Err is the error of the call when the retries were exhausted, nil otherwise
+type ProblemError struct {
+	Problem  *ProblemDetails
+	Response *Response
+	Err      error
+}
+
+func (e *ProblemError) Error() string {
+	msg := fmt.Sprintf("problem: status %d", e.Problem.Status)
+	if e.Problem.Title != "" {
+		msg += ": " + e.Problem.Title
+	}
+	if e.Problem.Detail != "" {
+		msg += ": " + e.Problem.Detail
+	}
+	if e.Problem.Type != "" {
+		msg += " (" + e.Problem.Type + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *ProblemError) Unwrap() error {
+	return e.Err
+}
+
+// WithProblemDetails makes the client return a *ProblemError along with the response
+// when an error response carries an RFC 7807 problem document.
+// When the retries are exhausted the error of the client is kept in Err of the *ProblemError
+func WithProblemDetails() OptionClient {
+	return func(r *ClientOptions) {
+		r.problemDetails = true
+	}
+}
+
+// isProblem reports whether the header announces a problem document
+func isProblem(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == ProblemContentType
+}
+
+// keepProblem returns the problem response of a retried attempt, restoring its body
+// so the retryable client can still drain it
+func keepProblem(resp *http.Response) *Response {
+	if resp == nil || resp.StatusCode < 400 || !isProblem(resp.Header) {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProblemSize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return nil
+	}
+
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// exhaustedProblem joins the problem of the last attempt to the error of a call whose retries were exhausted
+func exhaustedProblem(req *http.Request, err error) (*Response, error) {
+	state := getCallState(req.Context())
+	if state == nil || state.problem == nil {
+		return nil, err
+	}
+	problem, parseErr := ParseProblem(state.problem)
+	if parseErr != nil || problem == nil {
+		return nil, err
+	}
+
+	return state.problem, &ProblemError{Problem: problem, Response: state.problem, Err: err}
+}
+
+// ParseProblem returns the problem document of an error response, nil when it carries none
+func ParseProblem(resp *Response) (*ProblemDetails, error) {
+	if resp == nil || resp.StatusCode < 400 {
+		return nil, nil
+	}
+	if !isProblem(resp.Header) {
+		return nil, nil
+	}
+
+	problem := &ProblemDetails{}
+	if err := json.Unmarshal(resp.Body, problem); err != nil {
+		return nil, fmt.Errorf("problem: unable to decode problem document: %w", err)
+	}
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+
+	return problem, nil
+}
+
+// AsProblem returns the problem details reachable from err
+func AsProblem(err error) (*ProblemDetails, bool) {
+	var problemErr *ProblemError
+	if errors.As(err, &problemErr) {
+		return problemErr.Problem, true
+	}
+
+	return nil, false
+}
@@ -2,6 +2,7 @@ package request
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"io"
 	"log"
@@ -30,8 +31,16 @@ type Client interface {
 	GetStandardClient() *http.Client
 }
 
+// ContextClient is a Client whose calls can be bound to a context
+type ContextClient interface {
+	Client
+	SendWithContext(ctx context.Context, method, path string, opts SendOptions, body []byte) (*Response, error)
+}
+
 // SendOptions for attached data through a request
-// Example should be add query params (http://abcd.com?user=ec&limit=5) or header
+// Example should be add query params (http://abcd.com?user=ec&limit=5) or header.
+// The "settings" key overrides the client settings for the call, see SetTimeout,
+// and the "expect" key validates the response, see SetExpectation
 //
 //	SendOptions {
 //	 "queries": map[string]interface{}{
@@ -47,25 +56,71 @@ type SendOptions map[string]map[string]interface{}
 
 // WithTimeout sets timeout of the client options
 func WithTimeout(timeout time.Duration) OptionClient {
-	return func(r *retryablehttp.Client) {
+	return func(r *ClientOptions) {
 		r.HTTPClient.Timeout = timeout
 	}
 }
 
 // WithRetryMax sets max retry of the client options
 func WithRetryMax(retryMax int) OptionClient {
-	return func(r *retryablehttp.Client) {
+	return func(r *ClientOptions) {
 		r.RetryMax = retryMax
 	}
 }
 
 // OptionClient represents an option for the http client
-type OptionClient func(*retryablehttp.Client)
+type OptionClient func(*ClientOptions)
+
+// FromRetryableOption adapts an option written against the retryablehttp client,
+// the former form of OptionClient
+func FromRetryableOption(opt func(*retryablehttp.Client)) OptionClient {
+	return func(r *ClientOptions) {
+		opt(r.Client)
+	}
+}
+
+// TransportMiddleware wraps the transport sending each attempt of a request
+type TransportMiddleware func(http.RoundTripper) http.RoundTripper
+
+// WithTransportMiddleware adds a middleware around the transport.
+// The middlewares are applied in order once every option is set, the last one being the outermost
+func WithTransportMiddleware(m TransportMiddleware) OptionClient {
+	return func(r *ClientOptions) {
+		r.middlewares = append(r.middlewares, m)
+	}
+}
+
+// ClientOptions holds the settings of the http client.
+// The retryablehttp client is embedded so an option can tune it directly
+type ClientOptions struct {
+	*retryablehttp.Client
+
+	retryMethods       map[string]bool
+	idempotencyMethods map[string]bool
+	hedge              *hedger
+	metrics            Metrics
+	problemDetails     bool
+	middlewares        []TransportMiddleware
+	hooks              []Hooks
+	dialContext        DialContextFunc
+	unixSockets        map[string]string
+	resolve            map[string]string
+	retryBudget        *retryBudget
+	timing             bool
+}
+
+func newClientOptions(httpClient *retryablehttp.Client) *ClientOptions {
+	return &ClientOptions{
+		Client:       httpClient,
+		retryMethods: methodSet(DefaultRetryMethods),
+	}
+}
 
 type client struct {
 	debugEnable bool
 	logger      *logrus.Logger
 	HTTPClient  *http.Client
+	options     *ClientOptions
 }
 
 // Response struct
@@ -73,6 +128,8 @@ type Response struct {
 	Body       []byte
 	Header     http.Header
 	StatusCode int
+	// Timings of the attempts of the call, set with WithTiming
+	Timings []Timing
 }
 
 // NewClient init http client
@@ -84,14 +141,24 @@ func NewClient(optsClient ...OptionClient) Client {
 func NewClientWithDebug(debugEnable bool, optsClient ...OptionClient) Client {
 	clientlogger := logrus.New()
 	httpClient := retryablehttp.NewClient()
-	for _, optClient := range optsClient {
-		optClient(httpClient)
-	}
-
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
 	httpClient.HTTPClient.Transport = tr
+
+	options := newClientOptions(httpClient)
+	for _, optClient := range optsClient {
+		optClient(options)
+	}
+	options.applyDialer()
+	if options.timing {
+		httpClient.HTTPClient.Transport = &timingTransport{options: options, next: httpClient.HTTPClient.Transport}
+	}
+	for _, middleware := range options.middlewares {
+		httpClient.HTTPClient.Transport = middleware(httpClient.HTTPClient.Transport)
+	}
+
+	httpClient.CheckRetry = options.checkRetry(httpClient.CheckRetry)
 	httpClient.Logger = log.New(io.Discard, "", log.LstdFlags)
 	if debugEnable {
 		clientlogger.SetFormatter(&logrus.TextFormatter{
@@ -116,10 +183,12 @@ func NewClientWithDebug(debugEnable bool, optsClient ...OptionClient) Client {
 			}).Debug("Sending request")
 		}
 	}
+	httpClient.RequestLogHook = options.retryHook(httpClient.RequestLogHook)
 	return &client{
 		debugEnable: debugEnable,
 		logger:      clientlogger,
 		HTTPClient:  httpClient.StandardClient(),
+		options:     options,
 	}
 }
 
@@ -175,6 +244,11 @@ func (c client) Delete(targetURL string, opts SendOptions, body []byte) (*Respon
 
 // Send a request and returns response from target URL
 func (c client) Send(method, targetURL string, opts SendOptions, body []byte) (*Response, error) {
+	return c.SendWithContext(context.Background(), method, targetURL, opts, body)
+}
+
+// SendWithContext sends a request bound to ctx and returns response from target URL
+func (c client) SendWithContext(ctx context.Context, method, targetURL string, opts SendOptions, body []byte) (*Response, error) {
 	if c.debugEnable {
 		c.logger.WithFields(logrus.Fields{
 			"method": method,
@@ -183,6 +257,38 @@ func (c client) Send(method, targetURL string, opts SendOptions, body []byte) (*
 			"body":   string(body),
 		}).Debug("[Send]: http request")
 	}
+	var bBody io.Reader
+	if body != nil {
+		bBody = bytes.NewBuffer(body)
+	}
+
+	req, err := newRequest(ctx, method, targetURL, opts, bBody)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.options.setIdempotencyKey(req); err != nil {
+		return nil, err
+	}
+	req = req.WithContext(c.options.withCallState(req.Context(), req, opts))
+	if c.debugEnable {
+		c.logger.Debugf("request %+v", req)
+	}
+
+	c.options.onRequest(req)
+	var response *Response
+	if c.options.hedge != nil && req.Method == http.MethodGet {
+		response, err = c.hedge(req)
+	} else {
+		response, err = c.do(req)
+	}
+	err = validateResponse(opts, response, err)
+	c.options.onResult(req, response, err)
+
+	return response, err
+}
+
+// newRequest builds a request carrying the query params and headers of opts
+func newRequest(ctx context.Context, method, targetURL string, opts SendOptions, body io.Reader) (*http.Request, error) {
 	method = strings.ToUpper(method)
 	urlSchema, err := url.Parse(targetURL)
 	if err != nil {
@@ -200,12 +306,7 @@ func (c client) Send(method, targetURL string, opts SendOptions, body []byte) (*
 	}
 	requestAPIUrl := urlSchema.String()
 
-	var bBody io.Reader
-	if body != nil {
-		bBody = bytes.NewBuffer(body)
-	}
-
-	req, err := http.NewRequest(method, requestAPIUrl, bBody)
+	req, err := http.NewRequestWithContext(ctx, method, requestAPIUrl, body)
 	if err != nil {
 		return nil, err
 	}
@@ -215,15 +316,22 @@ func (c client) Send(method, targetURL string, opts SendOptions, body []byte) (*
 			req.Header.Set(key, val.(string))
 		}
 	}
-	if c.debugEnable {
-		c.logger.Debugf("request %+v", req)
-	}
 
-	netResponse, err := c.HTTPClient.Do(req)
+	return req, nil
+}
+
+// do sends the request and reads the whole response body
+func (c client) do(req *http.Request) (*Response, error) {
+	netResponse, err := c.httpClient(req).Do(req)
 	if err != nil {
-		return nil, err
+		return exhaustedProblem(req, err)
 	}
 
+	return c.readResponse(netResponse)
+}
+
+// readResponse reads and closes the response body
+func (c client) readResponse(netResponse *http.Response) (*Response, error) {
 	contents, err := io.ReadAll(netResponse.Body)
 	defer func() {
 		if err := netResponse.Body.Close(); err != nil {
@@ -240,6 +348,17 @@ func (c client) Send(method, targetURL string, opts SendOptions, body []byte) (*
 		Header:     netResponse.Header,
 		Body:       contents,
 	}
+	c.setTimings(response, netResponse)
+
+	if c.options.problemDetails {
+		problem, err := ParseProblem(response)
+		if err != nil {
+			return response, err
+		}
+		if problem != nil {
+			return response, &ProblemError{Problem: problem, Response: response}
+		}
+	}
 
 	return response, nil
 }
@@ -248,3 +367,35 @@ func (c client) Send(method, targetURL string, opts SendOptions, body []byte) (*
 func (c client) GetStandardClient() *http.Client {
 	return c.HTTPClient
 }
+
+// SendWithContext sends a request bound to ctx through c.
+// A Client not implementing ContextClient, e.g. a mock, is used through its standard client
+func SendWithContext(ctx context.Context, c Client, method, targetURL string, opts SendOptions, body []byte) (*Response, error) {
+	if cc, ok := c.(ContextClient); ok {
+		return cc.SendWithContext(ctx, method, targetURL, opts, body)
+	}
+	return streamingClient(c).SendWithContext(ctx, method, targetURL, opts, body)
+}
+
+// streamingClient returns the client sending the streamed calls of c, such as Download.
+// A Client not created by NewClient, e.g. a mock, is used through its standard client
+func streamingClient(c Client) client {
+	if cl, ok := c.(*client); ok {
+		return *cl
+	}
+
+	httpClient := c.GetStandardClient()
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient = httpClient
+	retryClient.RetryMax = 0
+	retryClient.Logger = log.New(io.Discard, "", log.LstdFlags)
+
+	return client{
+		logger:     logrus.New(),
+		HTTPClient: httpClient,
+		options:    newClientOptions(retryClient),
+	}
+}
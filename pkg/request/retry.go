@@ -0,0 +1,115 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// DefaultRetryMethods lists the idempotent methods retried by default
+var DefaultRetryMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+type callStateKey struct{}
+
+// callState is the state of a logical call shared by its attempts
+type callState struct {
+	method    string
+	host      string
+	retryable bool
+	// retryClient carries the settings of the call, nil to use the client ones
+	retryClient *retryablehttp.Client
+	// attempt is 0 for the first attempt, then the retry number
+	attempt int
+
+	// last retry decision, reported to the retry hooks
+	retryErr    error
+	retryStatus int
+	retryAt     time.Time
+
+	// problem is the problem response of the last attempt, kept with WithProblemDetails
+	problem *Response
+
+	// timings of the attempts, traced with WithTiming
+	timings []*attemptTrace
+}
+
+// WithRetryMethods sets the methods which are allowed to be retried.
+// A request carrying an Idempotency-Key header is retried whatever its method
+func WithRetryMethods(methods ...string) OptionClient {
+	return func(r *ClientOptions) {
+		r.retryMethods = methodSet(methods)
+	}
+}
+
+// withCallState attaches the state of a new call to the request context
+func (o *ClientOptions) withCallState(ctx context.Context, req *http.Request, opts SendOptions) context.Context {
+	return context.WithValue(ctx, callStateKey{}, &callState{
+		method:      req.Method,
+		host:        req.URL.Host,
+		retryable:   o.retryMethods[req.Method] || req.Header.Get(IdempotencyKeyHeader) != "",
+		retryClient: o.callClient(opts),
+	})
+}
+
+// getCallState returns the state of the call, nil for a request not sent by the client
+func getCallState(ctx context.Context) *callState {
+	state, _ := ctx.Value(callStateKey{}).(*callState)
+	return state
+}
+
+// checkRetry wraps the retry policy so non retryable requests are attempted once
+// and retries are kept within the retry budget
+func (o *ClientOptions) checkRetry(next retryablehttp.CheckRetry) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		shouldRetry, checkErr := next(ctx, resp, err)
+		if errors.Is(err, ErrDeadlineExhausted) {
+			return false, checkErr
+		}
+		if o.retryBudget != nil && !shouldRetry && err == nil && resp != nil && resp.StatusCode < http.StatusInternalServerError {
+			o.retryBudget.deposit()
+		}
+		state := getCallState(ctx)
+		if state == nil {
+			return shouldRetry, checkErr
+		}
+		if !state.retryable {
+			return false, checkErr
+		}
+		if shouldRetry && o.retryBudget != nil && !o.retryBudget.withdraw() {
+			o.incCounter(MetricRetriesSuppressed, map[string]string{"method": state.method, "host": state.host})
+			return false, checkErr
+		}
+		if o.problemDetails {
+			state.problem = nil
+			if shouldRetry {
+				state.problem = keepProblem(resp)
+			}
+		}
+		if shouldRetry {
+			state.retryErr, state.retryStatus, state.retryAt = err, 0, time.Now()
+			if resp != nil {
+				state.retryStatus = resp.StatusCode
+			}
+		}
+		return shouldRetry, checkErr
+	}
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[strings.ToUpper(method)] = true
+	}
+	return set
+}
@@ -0,0 +1,520 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchema is a compiled JSON Schema. The validation keywords supported are type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems, uniqueItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// multipleOf, allOf, anyOf, oneOf, not and $ref to local definitions ($defs or definitions).
+// Any other validation keyword is rejected by CompileJSONSchema rather than ignored
+type JSONSchema struct {
+	root *JSONSchema
+	// never is set by the `false` schema
+	never bool
+
+	types                []string
+	enum                 []interface{}
+	constValue           interface{}
+	hasConst             bool
+	properties           map[string]*JSONSchema
+	required             []string
+	additionalProperties *JSONSchema
+	items                *JSONSchema
+	minItems, maxItems   *int
+	uniqueItems          bool
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	multipleOf           *float64
+	allOf, anyOf, oneOf  []*JSONSchema
+	not                  *JSONSchema
+	ref                  string
+	defs                 map[string]*JSONSchema
+}
+
+// annotationKeywords do not take part in the validation
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// supportedKeywords are the keywords of jsonSchemaDoc
+var supportedKeywords = func() map[string]bool {
+	keywords := make(map[string]bool)
+	t := reflect.TypeOf(jsonSchemaDoc{})
+	for i := 0; i < t.NumField(); i++ {
+		keywords[t.Field(i).Tag.Get("json")] = true
+	}
+	return keywords
+}()
+
+type jsonSchemaDoc struct {
+	Type                 json.RawMessage        `json:"type"`
+	Enum                 []interface{}          `json:"enum"`
+	Const                json.RawMessage        `json:"const"`
+	Properties           map[string]*JSONSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties"`
+	Items                *JSONSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	UniqueItems          bool                   `json:"uniqueItems"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MultipleOf           *float64               `json:"multipleOf"`
+	AllOf                []*JSONSchema          `json:"allOf"`
+	AnyOf                []*JSONSchema          `json:"anyOf"`
+	OneOf                []*JSONSchema          `json:"oneOf"`
+	Not                  *JSONSchema            `json:"not"`
+	Ref                  string                 `json:"$ref"`
+	Defs                 map[string]*JSONSchema `json:"$defs"`
+	Definitions          map[string]*JSONSchema `json:"definitions"`
+}
+
+// CompileJSONSchema parses a JSON Schema document. It fails on the keywords not supported,
+// on unresolved references and on reference cycles which would never end
+func CompileJSONSchema(data []byte) (*JSONSchema, error) {
+	s := &JSONSchema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	s.setRoot(s)
+	if err := s.checkRefs(); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	return s, nil
+}
+
+// MustCompileJSONSchema is like CompileJSONSchema but panics on error
+func MustCompileJSONSchema(data []byte) *JSONSchema {
+	s, err := CompileJSONSchema(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface, booleans are valid schemas
+func (s *JSONSchema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = JSONSchema{}
+		return nil
+	case "false":
+		*s = JSONSchema{never: true}
+		return nil
+	}
+
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+	var unsupported []string
+	for keyword := range keywords {
+		if !supportedKeywords[keyword] && !annotationKeywords[keyword] {
+			unsupported = append(unsupported, keyword)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("unsupported keywords %q", unsupported)
+	}
+
+	var doc jsonSchemaDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*s = JSONSchema{
+		enum:                 doc.Enum,
+		properties:           doc.Properties,
+		required:             doc.Required,
+		additionalProperties: doc.AdditionalProperties,
+		items:                doc.Items,
+		minItems:             doc.MinItems,
+		maxItems:             doc.MaxItems,
+		uniqueItems:          doc.UniqueItems,
+		minLength:            doc.MinLength,
+		maxLength:            doc.MaxLength,
+		minimum:              doc.Minimum,
+		maximum:              doc.Maximum,
+		exclusiveMinimum:     doc.ExclusiveMinimum,
+		exclusiveMaximum:     doc.ExclusiveMaximum,
+		multipleOf:           doc.MultipleOf,
+		allOf:                doc.AllOf,
+		anyOf:                doc.AnyOf,
+		oneOf:                doc.OneOf,
+		not:                  doc.Not,
+		ref:                  doc.Ref,
+		defs:                 doc.Defs,
+	}
+	if s.defs == nil {
+		s.defs = doc.Definitions
+	}
+
+	if len(doc.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(doc.Type, &single); err == nil {
+			s.types = []string{single}
+		} else if err := json.Unmarshal(doc.Type, &s.types); err != nil {
+			return fmt.Errorf("invalid type: %s", doc.Type)
+		}
+	}
+	if len(doc.Const) > 0 {
+		if err := json.Unmarshal(doc.Const, &s.constValue); err != nil {
+			return err
+		}
+		s.hasConst = true
+	}
+	if doc.Pattern != "" {
+		pattern, err := regexp.Compile(doc.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", doc.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+
+	return nil
+}
+
+func (s *JSONSchema) setRoot(root *JSONSchema) {
+	if s == nil {
+		return
+	}
+	s.root = root
+	for _, sub := range s.properties {
+		sub.setRoot(root)
+	}
+	for _, sub := range s.defs {
+		sub.setRoot(root)
+	}
+	for _, list := range [][]*JSONSchema{s.allOf, s.anyOf, s.oneOf} {
+		for _, sub := range list {
+			sub.setRoot(root)
+		}
+	}
+	s.additionalProperties.setRoot(root)
+	s.items.setRoot(root)
+	s.not.setRoot(root)
+}
+
+// subschemas returns the schemas nested in s
+func (s *JSONSchema) subschemas() []*JSONSchema {
+	var subs []*JSONSchema
+	for _, sub := range s.properties {
+		subs = append(subs, sub)
+	}
+	for _, sub := range s.defs {
+		subs = append(subs, sub)
+	}
+	subs = append(subs, s.allOf...)
+	subs = append(subs, s.anyOf...)
+	subs = append(subs, s.oneOf...)
+	for _, sub := range []*JSONSchema{s.additionalProperties, s.items, s.not} {
+		if sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// sameValue returns the schemas applied to the same value as s, following its reference
+func (s *JSONSchema) sameValue() []*JSONSchema {
+	var next []*JSONSchema
+	if s.ref != "" {
+		next = append(next, s.resolve(s.ref))
+	}
+	next = append(next, s.allOf...)
+	next = append(next, s.anyOf...)
+	next = append(next, s.oneOf...)
+	if s.not != nil {
+		next = append(next, s.not)
+	}
+	return next
+}
+
+// checkRefs fails on unresolved references and on cycles of schemas applied to the same value,
+// such as a definition referencing itself, which validation would follow forever
+func (s *JSONSchema) checkRefs() error {
+	var all []*JSONSchema
+	var collect func(*JSONSchema) error
+	collect = func(node *JSONSchema) error {
+		if node.ref != "" && node.resolve(node.ref) == nil {
+			return fmt.Errorf("unresolved $ref %q", node.ref)
+		}
+		all = append(all, node)
+		for _, sub := range node.subschemas() {
+			if err := collect(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := collect(s); err != nil {
+		return err
+	}
+
+	const visiting, done = 1, 2
+	states := make(map[*JSONSchema]int)
+	// via is the last reference followed to reach node
+	var visit func(node *JSONSchema, via string) error
+	visit = func(node *JSONSchema, via string) error {
+		switch states[node] {
+		case visiting:
+			return fmt.Errorf("$ref cycle through %q", via)
+		case done:
+			return nil
+		}
+		if node.ref != "" {
+			via = node.ref
+		}
+		states[node] = visiting
+		for _, next := range node.sameValue() {
+			if err := visit(next, via); err != nil {
+				return err
+			}
+		}
+		states[node] = done
+		return nil
+	}
+	for _, node := range all {
+		if err := visit(node, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateJSON validates a JSON document and returns the problems found, prefixed by their JSON path
+func (s *JSONSchema) ValidateJSON(data []byte) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("schema: invalid JSON: %w", err)
+	}
+
+	var problems []string
+	s.validate(value, "$", &problems)
+
+	return problems, nil
+}
+
+func (s *JSONSchema) validate(value interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if s.never {
+		report("no value is allowed")
+		return
+	}
+	if s.ref != "" {
+		target := s.resolve(s.ref)
+		if target == nil {
+			report("unresolved $ref %q", s.ref)
+			return
+		}
+		target.validate(value, path, problems)
+	}
+
+	if len(s.types) > 0 && !s.matchesType(value) {
+		report("expected %s, got %s", strings.Join(s.types, " or "), jsonType(value))
+		return
+	}
+	if len(s.enum) > 0 {
+		found := false
+		for _, candidate := range s.enum {
+			if reflect.DeepEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value %s is not one of the enum values", jsonText(value))
+		}
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constValue, value) {
+		report("value %s is not %s", jsonText(value), jsonText(s.constValue))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.validateObject(v, path, problems)
+	case []interface{}:
+		s.validateArray(v, path, problems)
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			report("length %d is shorter than %d", length, *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			report("length %d is longer than %d", length, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("%q does not match pattern %q", v, s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			report("%v is less than minimum %v", v, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			report("%v is greater than maximum %v", v, *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			report("%v is not greater than %v", v, *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			report("%v is not less than %v", v, *s.exclusiveMaximum)
+		}
+		if s.multipleOf != nil && *s.multipleOf != 0 {
+			if q := v / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+				report("%v is not a multiple of %v", v, *s.multipleOf)
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(value, path, problems)
+	}
+	if len(s.anyOf) > 0 && s.countMatches(s.anyOf, value) == 0 {
+		report("value does not match any schema of anyOf")
+	}
+	if len(s.oneOf) > 0 {
+		if n := s.countMatches(s.oneOf, value); n != 1 {
+			report("value matches %d schemas of oneOf instead of exactly one", n)
+		}
+	}
+	if s.not != nil && s.countMatches([]*JSONSchema{s.not}, value) == 1 {
+		report("value must not match the schema of not")
+	}
+}
+
+func (s *JSONSchema) validateObject(obj map[string]interface{}, path string, problems *[]string) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if sub, ok := s.properties[name]; ok {
+			sub.validate(obj[name], path+"."+name, problems)
+		} else if s.additionalProperties != nil {
+			if s.additionalProperties.never {
+				*problems = append(*problems, fmt.Sprintf("%s: additional property %q is not allowed", path, name))
+				continue
+			}
+			s.additionalProperties.validate(obj[name], path+"."+name, problems)
+		}
+	}
+}
+
+func (s *JSONSchema) validateArray(arr []interface{}, path string, problems *[]string) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		*problems = append(*problems, fmt.Sprintf("%s: %d items, expected at least %d", path, len(arr), *s.minItems))
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		*problems = append(*problems, fmt.Sprintf("%s: %d items, expected at most %d", path, len(arr), *s.maxItems))
+	}
+	if s.uniqueItems {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					*problems = append(*problems, fmt.Sprintf("%s: items %d and %d are equal", path, i, j))
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
+
+func (s *JSONSchema) countMatches(schemas []*JSONSchema, value interface{}) int {
+	n := 0
+	for _, sub := range schemas {
+		var problems []string
+		sub.validate(value, "$", &problems)
+		if len(problems) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *JSONSchema) matchesType(value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range s.types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the schema of a local reference such as #/$defs/user
+func (s *JSONSchema) resolve(ref string) *JSONSchema {
+	root := s.root
+	if root == nil {
+		root = s
+	}
+	if ref == "#" {
+		return root
+	}
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return root.defs[strings.TrimPrefix(ref, prefix)]
+		}
+	}
+	return nil
+}
+
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func jsonText(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
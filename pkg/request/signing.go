@@ -0,0 +1,313 @@
+package request
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSignatureTolerance = 5 * time.Minute
+	defaultMaxSignedBodySize  = 10 << 20
+)
+
+// Errors returned by HMACVerifier.Verify
+var (
+	ErrSignatureMissing  = errors.New("hmac: missing signature or timestamp")
+	ErrSignatureInvalid  = errors.New("hmac: invalid signature")
+	ErrSignatureExpired  = errors.New("hmac: timestamp outside of tolerance")
+	ErrSignatureReplayed = errors.New("hmac: signature already used")
+)
+
+// CanonicalRequest holds the parts of a request covered by the signature
+type CanonicalRequest struct {
+	Method string
+	// Path is the escaped URL path
+	Path  string
+	Query url.Values
+	// Headers are the values of the signed headers, keyed by lower case name
+	Headers map[string]string
+	// SignedHeaders are the lower case names of the signed headers in signing order
+	SignedHeaders []string
+	// BodyHash is the hex encoded SHA-256 of the body
+	BodyHash  string
+	Timestamp string
+}
+
+// CanonicalString joins method, path, sorted query, signed headers,
+// timestamp and body hash with new lines
+func CanonicalString(c CanonicalRequest) string {
+	keys := make([]string, 0, len(c.Query))
+	for key := range c.Query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), c.Query[key]...)
+		sort.Strings(values)
+		for _, val := range values {
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(val))
+		}
+	}
+
+	lines := []string{strings.ToUpper(c.Method), c.Path, strings.Join(params, "&")}
+	for _, name := range c.SignedHeaders {
+		lines = append(lines, name+":"+strings.TrimSpace(c.Headers[name]))
+	}
+	lines = append(lines, c.Timestamp, c.BodyHash)
+
+	return strings.Join(lines, "\n")
+}
+
+// NonceStore remembers the signatures already accepted until they expire
+type NonceStore interface {
+	// Add records key and reports false when it is already known
+	Add(key string, expires time.Time) bool
+}
+
+// HMACOptions configures the request signing and its verification
+type HMACOptions struct {
+	Secret []byte
+	// KeyID is sent in KeyIDHeader when set so the receiver can select the secret
+	KeyID string
+	// Hash defaults to SHA-256
+	Hash func() hash.Hash
+	// Header names default to X-Signature, X-Timestamp and X-Key-Id
+	SignatureHeader string
+	TimestampHeader string
+	KeyIDHeader     string
+	// SignedHeaders are the headers covered by the signature, "Host" included
+	SignedHeaders []string
+	// Canonicalize builds the signed string, CanonicalString by default
+	Canonicalize func(CanonicalRequest) string
+	// Tolerance is the accepted clock skew of the verifier, 5 minutes by default
+	Tolerance time.Duration
+	// MaxBodySize bounds the body read by the verifier, 10MB by default
+	MaxBodySize int64
+	// Nonces rejects replayed signatures in the verifier, an in memory store by default
+	Nonces NonceStore
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+}
+
+func (o HMACOptions) withDefaults() HMACOptions {
+	if o.Hash == nil {
+		o.Hash = sha256.New
+	}
+	if o.SignatureHeader == "" {
+		o.SignatureHeader = "X-Signature"
+	}
+	if o.TimestampHeader == "" {
+		o.TimestampHeader = "X-Timestamp"
+	}
+	if o.KeyIDHeader == "" {
+		o.KeyIDHeader = "X-Key-Id"
+	}
+	if o.Canonicalize == nil {
+		o.Canonicalize = CanonicalString
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = defaultSignatureTolerance
+	}
+	if o.MaxBodySize <= 0 {
+		o.MaxBodySize = defaultMaxSignedBodySize
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	if o.Nonces == nil {
+		o.Nonces = &memoryNonceStore{seen: make(map[string]time.Time), now: o.Now}
+	}
+	signed := make([]string, len(o.SignedHeaders))
+	for i, name := range o.SignedHeaders {
+		signed[i] = strings.ToLower(name)
+	}
+	o.SignedHeaders = signed
+
+	return o
+}
+
+// sign computes the hex encoded signature of the request and restores its body
+func (o HMACOptions) sign(r *http.Request, host, timestamp string) (string, error) {
+	body, err := readBody(&r.Body)
+	if err != nil {
+		return "", err
+	}
+	bodyHash := sha256.Sum256(body)
+
+	headers := make(map[string]string, len(o.SignedHeaders))
+	for _, name := range o.SignedHeaders {
+		if name == "host" {
+			headers[name] = host
+			continue
+		}
+		headers[name] = r.Header.Get(name)
+	}
+
+	canonical := o.Canonicalize(CanonicalRequest{
+		Method:        r.Method,
+		Path:          r.URL.EscapedPath(),
+		Query:         r.URL.Query(),
+		Headers:       headers,
+		SignedHeaders: o.SignedHeaders,
+		BodyHash:      hex.EncodeToString(bodyHash[:]),
+		Timestamp:     timestamp,
+	})
+	mac := hmac.New(o.Hash, o.Secret)
+	mac.Write([]byte(canonical))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// readBody reads the whole body and replaces it with a reader over the same bytes
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(b))
+
+	return b, nil
+}
+
+// WithHMACSigning signs every attempt of the requests with the options' secret,
+// setting the timestamp, key id and signature headers
+func WithHMACSigning(opts HMACOptions) OptionClient {
+	opts = opts.withDefaults()
+	return WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &hmacTransport{opts: opts, next: next}
+	})
+}
+
+type hmacTransport struct {
+	opts HMACOptions
+	next http.RoundTripper
+}
+
+func (t *hmacTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	timestamp := strconv.FormatInt(t.opts.Now().Unix(), 10)
+	signature, err := t.opts.sign(req, host, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(t.opts.TimestampHeader, timestamp)
+	req.Header.Set(t.opts.SignatureHeader, signature)
+	if t.opts.KeyID != "" {
+		req.Header.Set(t.opts.KeyIDHeader, t.opts.KeyID)
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// HMACVerifier verifies the signature of incoming requests, e.g. webhooks
+type HMACVerifier struct {
+	opts HMACOptions
+}
+
+// NewHMACVerifier returns a verifier matching the requests signed with the same options
+func NewHMACVerifier(opts HMACOptions) *HMACVerifier {
+	return &HMACVerifier{opts: opts.withDefaults()}
+}
+
+// Verify checks the signature and timestamp of r and rejects a replayed signature.
+// The body is read and restored so the handler can still read it,
+// a body larger than MaxBodySize fails with an *http.MaxBytesError
+func (v *HMACVerifier) Verify(r *http.Request) error {
+	signature := r.Header.Get(v.opts.SignatureHeader)
+	timestamp := r.Header.Get(v.opts.TimestampHeader)
+	if signature == "" || timestamp == "" {
+		return ErrSignatureMissing
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("hmac: invalid timestamp %q", timestamp)
+	}
+	signedAt := time.Unix(unix, 0)
+	now := v.opts.Now()
+	if signedAt.Before(now.Add(-v.opts.Tolerance)) || signedAt.After(now.Add(v.opts.Tolerance)) {
+		return ErrSignatureExpired
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(nil, r.Body, v.opts.MaxBodySize)
+	}
+	expected, err := v.opts.sign(r, r.Host, timestamp)
+	if err != nil {
+		return fmt.Errorf("hmac: unable to read body: %w", err)
+	}
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrSignatureInvalid
+	}
+	// the normalized signature is stored so a replay cannot differ by its case
+	if !v.opts.Nonces.Add(expected, signedAt.Add(v.opts.Tolerance)) {
+		return ErrSignatureReplayed
+	}
+
+	return nil
+}
+
+// Middleware rejects the requests failing Verify with 401 Unauthorized,
+// or 413 Request Entity Too Large when the body exceeds MaxBodySize
+func (v *HMACVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			status := http.StatusUnauthorized
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// memoryNonceStore is the default NonceStore, it forgets expired keys as new ones are added.
+// Expiry follows the clock of the options as the expiry times derive from it
+type memoryNonceStore struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+func (s *memoryNonceStore) Add(key string, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, exp := range s.seen {
+		if now.After(exp) {
+			delete(s.seen, k)
+		}
+	}
+	if _, ok := s.seen[key]; ok {
+		return false
+	}
+	s.seen[key] = expires
+
+	return true
+}
@@ -0,0 +1,239 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSSERetryWait    = 3 * time.Second
+	defaultSSERetryWaitMax = 30 * time.Second
+	sseContentType         = "text/event-stream"
+)
+
+// ErrSSEClosed is returned by Subscribe when the server asked to stop reconnecting with 204 No Content
+var ErrSSEClosed = errors.New("sse: stream closed by the server")
+
+// Event is a server-sent event
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// SSEOptions configures Client.Subscribe
+type SSEOptions struct {
+	// SendOptions are the query params and headers of the stream request
+	SendOptions SendOptions
+	// LastEventID is sent in the Last-Event-ID header of the first connection
+	LastEventID string
+	// RetryWait is the reconnection delay until the server sends a retry field, 3s by default.
+	// It is doubled after each failed connection up to RetryWaitMax, 30s by default
+	RetryWait    time.Duration
+	RetryWaitMax time.Duration
+	// MaxReconnects stops after that many consecutive failed connections, unlimited when zero
+	MaxReconnects int
+}
+
+// Subscribe consumes the server-sent events stream of targetURL and calls handler for each event.
+// The stream is reopened with Last-Event-ID after a disconnection, it blocks until ctx is done
+// and returns nil then. The client timeout does not apply to the stream
+func Subscribe(ctx context.Context, c Client, targetURL string, opts SSEOptions, handler func(Event)) error {
+	return streamingClient(c).subscribe(ctx, targetURL, opts, handler)
+}
+
+func (c client) subscribe(ctx context.Context, targetURL string, opts SSEOptions, handler func(Event)) error {
+	if opts.RetryWait <= 0 {
+		opts.RetryWait = defaultSSERetryWait
+	}
+	if opts.RetryWaitMax <= 0 {
+		opts.RetryWaitMax = defaultSSERetryWaitMax
+	}
+
+	stream := &sseStream{
+		lastEventID: opts.LastEventID,
+		retry:       opts.RetryWait,
+	}
+	failures := 0
+	for {
+		delivered, err := c.subscribeOnce(ctx, targetURL, opts, stream, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var fatal *sseFatalError
+		if errors.As(err, &fatal) {
+			return fatal.err
+		}
+		if delivered {
+			failures = 0
+		} else {
+			failures++
+		}
+		if opts.MaxReconnects > 0 && failures > opts.MaxReconnects {
+			return fmt.Errorf("sse: giving up after %d reconnection(s): %w", opts.MaxReconnects, err)
+		}
+
+		wait := stream.retry
+		for i := 1; i < failures && wait < opts.RetryWaitMax; i++ {
+			wait *= 2
+		}
+		if wait > opts.RetryWaitMax {
+			wait = opts.RetryWaitMax
+		}
+		if c.debugEnable {
+			c.logger.WithError(err).WithFields(logrus.Fields{
+				"url":           targetURL,
+				"last_event_id": stream.lastEventID,
+				"wait":          wait,
+			}).Debug("[Subscribe]: reconnecting")
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// sseFatalError stops the reconnections
+type sseFatalError struct {
+	err error
+}
+
+func (e *sseFatalError) Error() string {
+	return e.err.Error()
+}
+
+// sseStream holds the state kept across connections
+type sseStream struct {
+	lastEventID string
+	retry       time.Duration
+}
+
+// subscribeOnce reads one connection until it ends, it reports whether events were delivered
+func (c client) subscribeOnce(ctx context.Context, targetURL string, opts SSEOptions, stream *sseStream, handler func(Event)) (bool, error) {
+	req, err := newRequest(ctx, http.MethodGet, targetURL, opts.SendOptions, nil)
+	if err != nil {
+		return false, &sseFatalError{err: err}
+	}
+	req.Header.Set("Accept", sseContentType)
+	req.Header.Set("Cache-Control", "no-cache")
+	if stream.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", stream.lastEventID)
+	}
+
+	httpClient := &http.Client{
+		Transport: c.options.HTTPClient.Transport,
+		Jar:       c.options.HTTPClient.Jar,
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return false, &sseFatalError{err: ErrSSEClosed}
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return false, fmt.Errorf("sse: unexpected status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return false, &sseFatalError{err: fmt.Errorf("sse: unexpected status %d", resp.StatusCode)}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != sseContentType {
+		return false, &sseFatalError{err: fmt.Errorf("sse: unexpected Content-Type %q", resp.Header.Get("Content-Type"))}
+	}
+
+	return readEvents(resp.Body, stream, handler)
+}
+
+// readEvents parses the event stream and dispatches each complete event
+func readEvents(body io.Reader, stream *sseStream, handler func(Event)) (bool, error) {
+	reader := bufio.NewReader(body)
+	delivered := false
+	var event Event
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return delivered, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if data.Len() > 0 {
+				event.ID = stream.lastEventID
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				handler(event)
+				delivered = true
+			}
+			event = Event{}
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				stream.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				stream.retry = time.Duration(ms) * time.Millisecond
+				event.Retry = stream.retry
+			}
+		}
+	}
+}
+
+// Events consumes the stream like Subscribe and delivers the events through a channel.
+// The events channel is closed when the subscription ends, its error, if any, is then sent on errc
+func Events(ctx context.Context, c Client, targetURL string, opts SSEOptions) (events <-chan Event, errc <-chan error) {
+	eventCh := make(chan Event)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(eventCh)
+		err := Subscribe(ctx, c, targetURL, opts, func(event Event) {
+			select {
+			case eventCh <- event:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	return eventCh, errCh
+}
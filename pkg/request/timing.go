@@ -0,0 +1,232 @@
+package request
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Duration metric names reported with WithTiming
+const (
+	MetricDNSDuration     = "http_client_dns_duration_seconds"
+	MetricConnectDuration = "http_client_connect_duration_seconds"
+	MetricTLSDuration     = "http_client_tls_handshake_duration_seconds"
+	MetricTTFBDuration    = "http_client_ttfb_duration_seconds"
+	MetricAttemptDuration = "http_client_attempt_duration_seconds"
+)
+
+// DurationMetrics is implemented by the metrics collectors also receiving durations,
+// such as histograms
+type DurationMetrics interface {
+	ObserveDuration(name string, d time.Duration, labels map[string]string)
+}
+
+// Timing is the breakdown of an attempt, the phases it did not go through are zero
+type Timing struct {
+	Attempt      int
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TTFB is the time to the first response byte from the start of the attempt
+	TTFB time.Duration
+	// Total runs until the response body is read, or the attempt fails
+	Total time.Duration
+	// Reused is set when the attempt went through an idle connection
+	Reused bool
+}
+
+// WithTiming traces every attempt with httptrace, the Timings of the response
+// holds the breakdown of the attempts of the call
+func WithTiming() OptionClient {
+	return func(r *ClientOptions) {
+		r.timing = true
+	}
+}
+
+type timingTransport struct {
+	options *ClientOptions
+	next    http.RoundTripper
+}
+
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state := getCallState(req.Context())
+	if state == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	trace := &attemptTrace{start: time.Now(), timing: Timing{Attempt: state.attempt}}
+	state.timings = append(state.timings, trace)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.finish(state, trace)
+		return nil, err
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: func() { t.finish(state, trace) }}
+
+	return resp, nil
+}
+
+// finish records the total of the attempt and reports its durations
+func (t *timingTransport) finish(state *callState, trace *attemptTrace) {
+	trace.mu.Lock()
+	if trace.done {
+		trace.mu.Unlock()
+		return
+	}
+	trace.done = true
+	trace.timing.Total = time.Since(trace.start)
+	timing := trace.timing
+	trace.mu.Unlock()
+
+	metrics, ok := t.options.metrics.(DurationMetrics)
+	if !ok {
+		return
+	}
+	labels := map[string]string{"method": state.method, "host": state.host}
+	for _, m := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{MetricDNSDuration, timing.DNS},
+		{MetricConnectDuration, timing.Connect},
+		{MetricTLSDuration, timing.TLSHandshake},
+		{MetricTTFBDuration, timing.TTFB},
+		{MetricAttemptDuration, timing.Total},
+	} {
+		if m.d > 0 {
+			metrics.ObserveDuration(m.name, m.d, labels)
+		}
+	}
+}
+
+// attemptTrace collects the timing of an attempt, the trace hooks may run concurrently
+type attemptTrace struct {
+	mu     sync.Mutex
+	start  time.Time
+	timing Timing
+	done   bool
+
+	dnsStart, connectStart, tlsStart time.Time
+}
+
+func (a *attemptTrace) clientTrace() *httptrace.ClientTrace {
+	since := func(start time.Time) time.Duration {
+		if start.IsZero() {
+			return 0
+		}
+		return time.Since(start)
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			a.mu.Lock()
+			a.dnsStart = time.Now()
+			a.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			a.mu.Lock()
+			a.timing.DNS = since(a.dnsStart)
+			a.mu.Unlock()
+		},
+		ConnectStart: func(_, _ string) {
+			a.mu.Lock()
+			if a.connectStart.IsZero() {
+				a.connectStart = time.Now()
+			}
+			a.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			a.mu.Lock()
+			if err == nil && a.timing.Connect == 0 {
+				a.timing.Connect = since(a.connectStart)
+			}
+			a.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			a.mu.Lock()
+			a.tlsStart = time.Now()
+			a.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			a.mu.Lock()
+			a.timing.TLSHandshake = since(a.tlsStart)
+			a.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			a.mu.Lock()
+			a.timing.Reused = info.Reused
+			a.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			a.mu.Lock()
+			a.timing.TTFB = time.Since(a.start)
+			a.mu.Unlock()
+		},
+	}
+}
+
+// timedBody calls done once the body is read to the end or closed
+type timedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// timingsSnapshot returns the timing of the attempts of the call
+func (s *callState) timingsSnapshot() []Timing {
+	timings := make([]Timing, len(s.timings))
+	for i, trace := range s.timings {
+		trace.mu.Lock()
+		timings[i] = trace.timing
+		trace.mu.Unlock()
+	}
+	return timings
+}
+
+// setTimings attaches the timing of the attempts of the call to the response
+func (c client) setTimings(response *Response, netResponse *http.Response) {
+	if !c.options.timing || netResponse.Request == nil {
+		return
+	}
+	state := getCallState(netResponse.Request.Context())
+	if state == nil {
+		return
+	}
+
+	response.Timings = state.timingsSnapshot()
+	if c.debugEnable {
+		for _, timing := range response.Timings {
+			c.logger.WithFields(logrus.Fields{
+				"host":    state.host,
+				"attempt": timing.Attempt,
+				"dns":     timing.DNS,
+				"connect": timing.Connect,
+				"tls":     timing.TLSHandshake,
+				"ttfb":    timing.TTFB,
+				"total":   timing.Total,
+				"reused":  timing.Reused,
+			}).Debug("[Client.Send]: timing")
+		}
+	}
+}
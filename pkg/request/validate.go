@@ -0,0 +1,104 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ExpectParam is the SendOptions key of the response expectation, see SetExpectation
+const ExpectParam = "expect"
+
+const expectationKey = "expectation"
+
+// Expectation declares what a valid response looks like
+type Expectation struct {
+	// StatusCodes lists the accepted status codes, any status is accepted when empty
+	StatusCodes []int
+	// Headers lists the headers the response must carry
+	Headers []string
+	// HeaderValues maps a header to its expected value
+	HeaderValues map[string]string
+	// Schema validates the JSON body when set
+	Schema *JSONSchema
+}
+
+// ValidationError is returned with a response failing its expectation
+type ValidationError struct {
+	Problems []string
+	Response *Response
+}
+
+func (e *ValidationError) Error() string {
+	return "validation: " + strings.Join(e.Problems, "; ")
+}
+
+// SetExpectation makes the call return a *ValidationError along with a response not matching e
+func (opt SendOptions) SetExpectation(e Expectation) SendOptions {
+	newOpt := opt
+	if newOpt == nil {
+		newOpt = make(SendOptions)
+	}
+	newOpt[ExpectParam] = map[string]interface{}{expectationKey: e}
+
+	return newOpt
+}
+
+// Validate checks the response against e and returns a *ValidationError listing every problem found
+func (r *Response) Validate(e Expectation) error {
+	var problems []string
+
+	if len(e.StatusCodes) > 0 && !containsStatus(e.StatusCodes, r.StatusCode) {
+		codes := make([]string, len(e.StatusCodes))
+		for i, code := range e.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		problems = append(problems, fmt.Sprintf("status %d, expected %s", r.StatusCode, strings.Join(codes, " or ")))
+	}
+	for _, name := range e.Headers {
+		if r.Header.Get(name) == "" {
+			problems = append(problems, fmt.Sprintf("missing header %s", http.CanonicalHeaderKey(name)))
+		}
+	}
+	for name, want := range e.HeaderValues {
+		if got := r.Header.Get(name); got != want {
+			problems = append(problems, fmt.Sprintf("header %s is %q, expected %q", http.CanonicalHeaderKey(name), got, want))
+		}
+	}
+	if e.Schema != nil {
+		schemaProblems, err := e.Schema.ValidateJSON(r.Body)
+		if err != nil {
+			problems = append(problems, "body: "+err.Error())
+		}
+		for _, problem := range schemaProblems {
+			problems = append(problems, "body "+problem)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems, Response: r}
+}
+
+// validateResponse applies the expectation of opts to a response received without error
+func validateResponse(opts SendOptions, response *Response, err error) error {
+	if err != nil || response == nil {
+		return err
+	}
+	e, ok := opts[ExpectParam][expectationKey].(Expectation)
+	if !ok {
+		return nil
+	}
+	return response.Validate(e)
+}
+
+func containsStatus(codes []int, status int) bool {
+	for _, code := range codes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}